@@ -0,0 +1,187 @@
+// Command report writes a report to a file or standard output, for scripted
+// exports such as a nightly sales file.
+//
+//	report -report sales -preset yesterday -format csv -out sales.csv
+//	report -report performance -from 2025-04-01 -to 2025-04-30 -format json
+//	report -report tax -preset last-month -format xlsx -out ppn.xlsx
+//	report -report valuation -from 2025-03-31 -format xlsx -out stock.xlsx
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/config"
+	"github.com/hendrisulistya/cashier-app/db"
+	"github.com/hendrisulistya/cashier-app/reports"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Report names accepted by -report
+const (
+	reportSales       = "sales"
+	reportPerformance = "performance"
+	reportDaily       = "daily"
+	reportCashiers    = "cashiers"
+	reportX           = "x-report"
+	reportValuation   = "valuation"
+	reportTax         = "tax"
+)
+
+var reportNames = []string{
+	reportSales, reportPerformance, reportDaily, reportCashiers, reportValuation, reportTax, reportX,
+}
+
+// presets maps -preset values to report range presets
+var presets = map[string]string{
+	"today":      types.RangeToday,
+	"yesterday":  types.RangeYesterday,
+	"this-week":  types.RangeThisWeek,
+	"this-month": types.RangeThisMonth,
+	"last-month": types.RangeLastMonth,
+}
+
+func main() {
+	name := flag.String("report", reportSales, "report to produce: "+strings.Join(reportNames, ", "))
+	preset := flag.String("preset", "", "date range preset: today, yesterday, this-week, this-month or last-month")
+	from := flag.String("from", "", "first day of the range (YYYY-MM-DD)")
+	to := flag.String("to", "", "last day of the range (YYYY-MM-DD), defaults to -from")
+	compare := flag.String("compare", "", "compare sales with the previous period or last-year")
+	format := flag.String("format", reports.FormatText, "output format: "+strings.Join(reports.Formats, ", "))
+	out := flag.String("out", "", "file to write, defaults to standard output")
+	deadDays := flag.Int("dead-days", 30, "days without sales before stock counts as dead stock")
+	flag.Parse()
+
+	if err := run(*name, *preset, *from, *to, *compare, *format, *out, *deadDays); err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(name, preset, from, to, compare, format, out string, deadDays int) error {
+	// Bad arguments are rejected before any output file is touched
+	if !slices.Contains(reportNames, name) {
+		return fmt.Errorf("unknown report %q", name)
+	}
+	if !slices.Contains(reports.Formats, strings.ToLower(format)) {
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	// The report reads an existing database, so migrations are not run here
+	database, err := db.NewConnection(config.LoadConfig())
+	if err != nil {
+		return fmt.Errorf("could not connect to database: %v", err)
+	}
+	defer database.Close()
+
+	settings, err := db.GetSettings(database)
+	if err != nil {
+		return fmt.Errorf("could not load settings: %v", err)
+	}
+	loc := settings.Location()
+
+	period, err := parseRange(preset, from, to, loc)
+	if err != nil {
+		return err
+	}
+
+	var report reports.Report
+	switch name {
+	case reportSales:
+		var against *types.TimeRange
+		switch compare {
+		case "":
+		case "previous":
+			previous := period.Previous()
+			against = &previous
+		case "last-year":
+			lastYear := period.LastYear()
+			against = &lastYear
+		default:
+			return fmt.Errorf("unknown comparison %q", compare)
+		}
+		report, err = reports.LoadSales(database, period, against)
+	case reportPerformance:
+		report, err = reports.LoadPerformance(database, period, deadDays, time.Now())
+	case reportDaily:
+		report, err = reports.LoadTrends(database, period)
+	case reportCashiers:
+		report, err = reports.LoadCashiers(database, period)
+	case reportValuation:
+		// Valued as of the end of the range's last day
+		report, err = reports.LoadValuation(database, period.LastDay())
+	case reportTax:
+		report, err = reports.LoadTax(database, period)
+	case reportX:
+		report, err = reports.LoadXReport(database, loc)
+	default:
+		return fmt.Errorf("unknown report %q", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		return reports.Render(os.Stdout, format, report)
+	}
+	return writeFile(out, format, report)
+}
+
+// writeFile renders the report to a temporary file beside path and renames it
+// into place, so a failed run never leaves a partial file behind
+func writeFile(path, format string, report reports.Report) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := reports.Render(f, format, report); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// parseRange returns the range for a preset or for the from and to dates in
+// the store time zone. With neither, the range is today.
+func parseRange(preset, from, to string, loc *time.Location) (types.TimeRange, error) {
+	if preset != "" {
+		p, ok := presets[preset]
+		if !ok {
+			return types.TimeRange{}, fmt.Errorf("unknown preset %q", preset)
+		}
+		return types.PresetRange(p, time.Now(), loc), nil
+	}
+	if from == "" {
+		return types.PresetRange(types.RangeToday, time.Now(), loc), nil
+	}
+	if to == "" {
+		to = from
+	}
+
+	first, err := time.ParseInLocation("2006-01-02", from, loc)
+	if err != nil {
+		return types.TimeRange{}, fmt.Errorf("invalid -from date %q", from)
+	}
+	last, err := time.ParseInLocation("2006-01-02", to, loc)
+	if err != nil {
+		return types.TimeRange{}, fmt.Errorf("invalid -to date %q", to)
+	}
+	if last.Before(first) {
+		return types.TimeRange{}, fmt.Errorf("-to is before -from")
+	}
+	return types.NewTimeRange(first, last, loc), nil
+}
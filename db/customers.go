@@ -0,0 +1,76 @@
+package db
+
+import (
+	"database/sql"
+	"math"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// InvoiceTotals holds the amounts printed on an invoice once loyalty points
+// have been applied
+type InvoiceTotals struct {
+	Subtotal       float64
+	Discount       float64
+	TaxAmount      float64
+	Total          float64
+	PointsRedeemed int
+	PointsPayment  float64
+	AmountDue      float64
+	PointsEarned   int
+	PointsBalance  int
+}
+
+func GetCustomerByPhone(db *sql.DB, phone string) (types.Customer, error) {
+	var c types.Customer
+	var email sql.NullString
+	err := db.QueryRow(`
+		SELECT id, name, phone, email, points
+		FROM customers WHERE phone = $1`, phone).
+		Scan(&c.ID, &c.Name, &c.Phone, &email, &c.Points)
+	c.Email = email.String
+	return c, err
+}
+
+func AddCustomer(db *sql.DB, customer types.Customer) (int, error) {
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO customers (name, phone, email)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id`,
+		customer.Name, customer.Phone, customer.Email).Scan(&id)
+	return id, err
+}
+
+// CalculateInvoiceTotals applies tax and loyalty redemption to a subtotal.
+// Redeemed points are capped so they never exceed the amount they pay for.
+func CalculateInvoiceTotals(settings Settings, subtotal float64, pointsRedeemed int) InvoiceTotals {
+	totals := InvoiceTotals{Subtotal: subtotal}
+	if !settings.LoyaltyEnabled || settings.LoyaltyPointValue <= 0 || pointsRedeemed < 0 {
+		pointsRedeemed = 0
+	}
+
+	if settings.LoyaltyRedeemMode == "payment" {
+		totals.TaxAmount = subtotal * (settings.TaxPercentage / 100)
+		totals.Total = subtotal + totals.TaxAmount
+		if pointsRedeemed > 0 {
+			maxPoints := int(math.Floor(totals.Total / settings.LoyaltyPointValue))
+			totals.PointsRedeemed = min(pointsRedeemed, maxPoints)
+			totals.PointsPayment = float64(totals.PointsRedeemed) * settings.LoyaltyPointValue
+		}
+	} else {
+		if pointsRedeemed > 0 {
+			maxPoints := int(math.Floor(subtotal / settings.LoyaltyPointValue))
+			totals.PointsRedeemed = min(pointsRedeemed, maxPoints)
+			totals.Discount = float64(totals.PointsRedeemed) * settings.LoyaltyPointValue
+		}
+		totals.TaxAmount = (subtotal - totals.Discount) * (settings.TaxPercentage / 100)
+		totals.Total = subtotal - totals.Discount + totals.TaxAmount
+	}
+	totals.AmountDue = totals.Total - totals.PointsPayment
+
+	if settings.LoyaltyEnabled && settings.LoyaltyEarnAmount > 0 {
+		totals.PointsEarned = int(math.Floor(totals.AmountDue / settings.LoyaltyEarnAmount))
+	}
+	return totals
+}
@@ -3,7 +3,11 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"strconv"
+	"time"
+	// Embedded zone data so the store time zone resolves on any platform
+	_ "time/tzdata"
 
 	"github.com/hendrisulistya/cashier-app/config"
 	"github.com/hendrisulistya/cashier-app/types"
@@ -16,6 +20,27 @@ type Settings struct {
 	StorePhone    string
 	TaxPercentage float64
 	InvoicePrefix string
+
+	LoyaltyEnabled    bool
+	LoyaltyEarnAmount float64
+	LoyaltyPointValue float64
+	LoyaltyRedeemMode string
+
+	SupervisorPIN string
+
+	ExpiryLookaheadDays int
+
+	TimeZone string
+}
+
+// Location returns the store's time zone, or UTC when the setting is not a
+// known zone
+func (s Settings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil || s.TimeZone == "" {
+		return time.UTC
+	}
+	return loc
 }
 
 func NewConnection(config *config.DBConfig) (*sql.DB, error) {
@@ -35,8 +60,31 @@ func NewConnection(config *config.DBConfig) (*sql.DB, error) {
 	return db, nil
 }
 
+// GetProducts returns the products that are on sale
 func GetProducts(db *sql.DB) ([]types.Product, error) {
-	rows, err := db.Query("SELECT id, name, price, stock FROM products")
+	return queryProducts(db, "WHERE active")
+}
+
+// GetAllProducts returns every product, including archived ones
+func GetAllProducts(db *sql.DB) ([]types.Product, error) {
+	return queryProducts(db, "")
+}
+
+// queryProducts loads products matching a WHERE clause. Products made to
+// order take their cost from their recipe.
+func queryProducts(db *sql.DB, where string) ([]types.Product, error) {
+	rows, err := db.Query(`
+		SELECT id, COALESCE(sku, ''), name, price, stock, unit, quantity_precision, reorder_point, reorder_quantity,
+			category, shelf, COALESCE(parent_id, 0), variant_name,
+			CASE WHEN track_stock THEN cost ELSE COALESCE((
+				SELECT SUM(rc.quantity * c.cost)
+				FROM recipe_components rc
+				JOIN products c ON c.id = rc.component_id
+				WHERE rc.product_id = products.id), cost) END,
+			active, track_stock
+		FROM products
+		` + where + `
+		ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +93,8 @@ func GetProducts(db *sql.DB) ([]types.Product, error) {
 	var products []types.Product
 	for rows.Next() {
 		var p types.Product
-		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock)
+		err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Price, &p.Stock, &p.Unit, &p.Precision, &p.ReorderPoint, &p.ReorderQuantity,
+			&p.Category, &p.Shelf, &p.ParentID, &p.VariantName, &p.Cost, &p.Active, &p.TrackStock)
 		if err != nil {
 			return nil, err
 		}
@@ -54,73 +103,297 @@ func GetProducts(db *sql.DB) ([]types.Product, error) {
 	return products, nil
 }
 
-func SaveSale(db *sql.DB, cartItems []types.CartItem) (int, error) {
+// SaveSale records a sale and takes its items out of stock. If a price change
+// has come due since an item was added, nothing is saved and ErrPriceChanged
+// is returned, so the customer is never charged a price they weren't shown.
+func SaveSale(db *sql.DB, cartItems []types.CartItem, customerID int, username string) (int, error) {
 	tx, err := db.Begin()
 	if err != nil {
 		return 0, err
 	}
 	defer tx.Rollback()
 
+	if err := applyScheduledPrices(tx); err != nil {
+		return 0, err
+	}
+	for _, item := range cartItems {
+		price, err := currentPrice(tx, item.Product.ID)
+		if err != nil {
+			return 0, err
+		}
+		if math.Abs(price-item.Product.Price) >= 0.005 {
+			return 0, ErrPriceChanged
+		}
+	}
+
 	// Calculate total
 	var total float64
 	for _, item := range cartItems {
-		total += item.Product.Price * float64(item.Quantity)
+		total += item.Subtotal()
 	}
 
 	// Insert sale
 	var saleID int
-	err = tx.QueryRow("INSERT INTO sales (total_amount) VALUES ($1) RETURNING id", total).Scan(&saleID)
+	err = tx.QueryRow("INSERT INTO sales (total_amount, customer_id, cashier) VALUES ($1, NULLIF($2, 0), $3) RETURNING id",
+		total, customerID, username).Scan(&saleID)
 	if err != nil {
 		return 0, err
 	}
 
 	// Insert sale items
 	for _, item := range cartItems {
-		_, err = tx.Exec(`
-			INSERT INTO sale_items (sale_id, product_id, quantity, price_at_sale)
-			SELECT $1, id, $2, $3
-			FROM products WHERE name = $4`,
-			saleID, item.Quantity, item.Product.Price, item.Product.Name)
+		var cost float64
+		var trackStock bool
+		err = tx.QueryRow("SELECT cost, track_stock FROM products WHERE id = $1", item.Product.ID).Scan(&cost, &trackStock)
 		if err != nil {
 			return 0, err
 		}
+		recipe, err := getRecipe(tx, item.Product.ID)
+		if err != nil {
+			return 0, err
+		}
+		if len(recipe) > 0 {
+			if !trackStock {
+				cost = 0
+			}
+			cost += types.RecipeCost(recipe)
+		}
 
-		// Update stock
-		_, err = tx.Exec(`
-			UPDATE products
-			SET stock = stock - $1
-			WHERE name = $2`,
-			item.Quantity, item.Product.Name)
+		// Sale items are stored in base units so reports add up across
+		// packages. The cost is snapshotted so later receipts don't change
+		// the profit of past sales.
+		quantity := item.BaseQuantity()
+		var packageID int
+		var packageName string
+		var packageQuantity sql.NullFloat64
+		if item.Package != nil {
+			packageID = item.Package.ID
+			packageName = item.Package.Name
+			packageQuantity = sql.NullFloat64{Float64: item.Quantity, Valid: true}
+		}
+
+		var saleItemID int
+		err = tx.QueryRow(`
+			INSERT INTO sale_items (sale_id, product_id, quantity, price_at_sale, cost_at_sale,
+				package_id, package_name, package_quantity)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, $8)
+			RETURNING id`,
+			saleID, item.Product.ID, quantity, item.Subtotal()/quantity, cost,
+			packageID, packageName, packageQuantity).Scan(&saleItemID)
 		if err != nil {
 			return 0, err
 		}
+
+		for _, modifier := range item.Modifiers {
+			_, err = tx.Exec(`
+				INSERT INTO sale_item_modifiers (sale_item_id, modifier_option_id, name, price_delta)
+				VALUES ($1, $2, $3, $4)`,
+				saleItemID, modifier.ID, modifier.Name, modifier.PriceDelta)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		// Update stock
+		reference := fmt.Sprintf("Sale #%d", saleID)
+		if trackStock {
+			if err := consumeLots(tx, saleItemID, item.Product, quantity, item.AllowExpired); err != nil {
+				return 0, err
+			}
+			err = RecordStockMovement(tx, types.StockMovement{
+				ProductID: item.Product.ID,
+				Type:      types.MovementSale,
+				Quantity:  -quantity,
+				Username:  username,
+				Reference: reference,
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		for _, c := range recipe {
+			used := c.Quantity * quantity
+			if err := consumeLots(tx, saleItemID, c.Component, used, item.AllowExpired); err != nil {
+				return 0, err
+			}
+			err = RecordStockMovement(tx, types.StockMovement{
+				ProductID: c.Component.ID,
+				Type:      types.MovementSale,
+				Quantity:  -used,
+				Username:  username,
+				Reference: reference,
+				Note:      item.Product.Name,
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
 	}
 
 	return saleID, tx.Commit()
 }
 
-func AddProduct(db *sql.DB, product types.Product) error {
-	_, err := db.Exec(`
-		INSERT INTO products (name, price, stock)
-		VALUES ($1, $2, $3)`,
-		product.Name, product.Price, product.Stock)
-	return err
+// AddProduct creates a product and records its opening stock in the ledger
+func AddProduct(db *sql.DB, product types.Product, username string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := insertProduct(tx, product, username); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertProduct creates a product inside a transaction, records its opening
+// stock in the ledger and returns its ID
+func insertProduct(tx *sql.Tx, product types.Product, username string) (int, error) {
+	var productID int
+	err := tx.QueryRow(`
+		INSERT INTO products (sku, name, price, stock, unit, quantity_precision, reorder_point, reorder_quantity,
+			category, shelf, parent_id, variant_name, cost)
+		VALUES (NULLIF($1, ''), $2, $3, 0, $4, $5, $6, $7, $8, $9, NULLIF($10, 0), $11, $12)
+		RETURNING id`,
+		product.SKU, product.Name, product.Price, product.Unit, product.Precision,
+		product.ReorderPoint, product.ReorderQuantity, product.Category, product.Shelf,
+		product.ParentID, product.VariantName, product.Cost).Scan(&productID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO product_prices (product_id, price, effective_from, created_by)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, $3)`,
+		productID, product.Price, username)
+	if err != nil {
+		return 0, err
+	}
+
+	if product.Stock != 0 {
+		err = RecordStockMovement(tx, types.StockMovement{
+			ProductID: productID,
+			Type:      types.MovementAdjustment,
+			Quantity:  product.Stock,
+			Username:  username,
+			Note:      "Opening stock",
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return productID, nil
+}
+
+// UpdateProduct updates a product's details. Stock is changed through
+// AdjustStock so every change is recorded in the ledger, and a new price
+// takes effect immediately and is added to the price history.
+func UpdateProduct(db *sql.DB, product types.Product, username string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateProduct(tx, product, username); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func UpdateProduct(db *sql.DB, product types.Product) error {
-	_, err := db.Exec(`
+func updateProduct(q queryer, product types.Product, username string) error {
+	_, err := q.Exec(`
+		INSERT INTO product_prices (product_id, price, effective_from, created_by)
+		SELECT id, $2::numeric, CURRENT_TIMESTAMP, $3
+		FROM products
+		WHERE id = $1 AND price <> $2::numeric`,
+		product.ID, product.Price, username)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`
 		UPDATE products
-		SET name = $1, price = $2, stock = $3
-		WHERE id = $4`,
-		product.Name, product.Price, product.Stock, product.ID)
+		SET sku = NULLIF($1, ''), name = $2, price = $3, unit = $4, quantity_precision = $5,
+			reorder_point = $6, reorder_quantity = $7, category = $8, shelf = $9, cost = $10
+		WHERE id = $11`,
+		product.SKU, product.Name, product.Price, product.Unit, product.Precision,
+		product.ReorderPoint, product.ReorderQuantity, product.Category, product.Shelf, product.Cost,
+		product.ID)
 	return err
 }
 
-func DeleteProduct(db *sql.DB, id int) error {
-	_, err := db.Exec("DELETE FROM products WHERE id = $1", id)
+// CountLowStockProducts returns how many products are at or below their
+// reorder point
+func CountLowStockProducts(db *sql.DB) (int, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM products
+		WHERE active AND track_stock AND reorder_point > 0 AND stock <= reorder_point`).Scan(&count)
+	return count, err
+}
+
+// ArchiveProduct hides a product and its variants from sale while keeping
+// them in reports and invoice history
+func ArchiveProduct(db *sql.DB, id int) error {
+	_, err := db.Exec("UPDATE products SET active = FALSE WHERE id = $1 OR parent_id = $1", id)
 	return err
 }
 
+// RestoreProduct puts an archived product and its variants back on sale
+func RestoreProduct(db *sql.DB, id int) error {
+	_, err := db.Exec("UPDATE products SET active = TRUE WHERE id = $1 OR parent_id = $1", id)
+	return err
+}
+
+// DeleteProduct permanently removes a product that has never been sold,
+// together with its stock ledger. Products with history must be archived.
+func DeleteProduct(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sold, variants, purchased, counted, ingredient bool
+	err = tx.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM sale_items WHERE product_id = $1),
+			EXISTS (SELECT 1 FROM products WHERE parent_id = $1),
+			EXISTS (SELECT 1 FROM purchase_order_lines WHERE product_id = $1),
+			EXISTS (SELECT 1 FROM stocktake_lines WHERE product_id = $1),
+			EXISTS (SELECT 1 FROM recipe_components WHERE component_id = $1)`, id).
+		Scan(&sold, &variants, &purchased, &counted, &ingredient)
+	if err != nil {
+		return err
+	}
+	switch {
+	case sold:
+		return fmt.Errorf("product has been sold; archive it instead")
+	case variants:
+		return fmt.Errorf("product has variants; delete them first")
+	case purchased:
+		return fmt.Errorf("product is on a purchase order; archive it instead")
+	case counted:
+		return fmt.Errorf("product is on a stocktake; archive it instead")
+	case ingredient:
+		return fmt.Errorf("product is used in a recipe; remove it from the recipe first")
+	}
+
+	if _, err := tx.Exec("DELETE FROM stock_movements WHERE product_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM products WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func GetSettings(db *sql.DB) (Settings, error) {
 	settings := Settings{}
 	rows, err := db.Query("SELECT key, value FROM settings")
@@ -146,6 +419,20 @@ func GetSettings(db *sql.DB) (Settings, error) {
 			settings.TaxPercentage, _ = strconv.ParseFloat(value, 64)
 		case "invoice_prefix":
 			settings.InvoicePrefix = value
+		case "loyalty_enabled":
+			settings.LoyaltyEnabled, _ = strconv.ParseBool(value)
+		case "loyalty_earn_amount":
+			settings.LoyaltyEarnAmount, _ = strconv.ParseFloat(value, 64)
+		case "loyalty_point_value":
+			settings.LoyaltyPointValue, _ = strconv.ParseFloat(value, 64)
+		case "loyalty_redeem_mode":
+			settings.LoyaltyRedeemMode = value
+		case "supervisor_pin":
+			settings.SupervisorPIN = value
+		case "expiry_lookahead_days":
+			settings.ExpiryLookaheadDays, _ = strconv.Atoi(value)
+		case "time_zone":
+			settings.TimeZone = value
 		}
 	}
 	return settings, nil
@@ -184,30 +471,73 @@ func GenerateInvoiceNumber(db *sql.DB) (string, error) {
 	return fmt.Sprintf("%s%06d", prefix, newNum), nil
 }
 
-func SaveInvoice(db *sql.DB, saleID int, invoiceNumber string, payment float64, change float64) error {
+func SaveInvoice(db *sql.DB, saleID int, invoiceNumber string, payment float64, pointsRedeemed int, paymentMethod string) (InvoiceTotals, error) {
 	settings, err := GetSettings(db)
 	if err != nil {
-		return err
+		return InvoiceTotals{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return InvoiceTotals{}, err
 	}
+	defer tx.Rollback()
 
 	var subtotal float64
-	err = db.QueryRow("SELECT total_amount FROM sales WHERE id = $1", saleID).Scan(&subtotal)
+	var customerID sql.NullInt64
+	err = tx.QueryRow("SELECT total_amount, customer_id FROM sales WHERE id = $1", saleID).Scan(&subtotal, &customerID)
 	if err != nil {
-		return err
+		return InvoiceTotals{}, err
 	}
 
-	taxAmount := subtotal * (settings.TaxPercentage / 100)
-	totalAmount := subtotal + taxAmount
+	// Points can only be redeemed against a customer's available balance
+	var customerName sql.NullString
+	var balance int
+	if customerID.Valid {
+		err = tx.QueryRow("SELECT name, points FROM customers WHERE id = $1 FOR UPDATE", customerID.Int64).
+			Scan(&customerName, &balance)
+		if err != nil {
+			return InvoiceTotals{}, err
+		}
+		pointsRedeemed = min(pointsRedeemed, balance)
+	} else {
+		pointsRedeemed = 0
+	}
+
+	totals := CalculateInvoiceTotals(settings, subtotal, pointsRedeemed)
+	if payment < totals.AmountDue {
+		return InvoiceTotals{}, fmt.Errorf("payment of Rp%.2f is less than the Rp%.2f due", payment, totals.AmountDue)
+	}
+	change := payment - totals.AmountDue
+
+	var pointsBalance sql.NullInt64
+	if customerID.Valid {
+		totals.PointsBalance = balance - totals.PointsRedeemed + totals.PointsEarned
+		pointsBalance = sql.NullInt64{Int64: int64(totals.PointsBalance), Valid: true}
+		_, err = tx.Exec("UPDATE customers SET points = $1 WHERE id = $2", totals.PointsBalance, customerID.Int64)
+		if err != nil {
+			return InvoiceTotals{}, err
+		}
+	} else {
+		totals.PointsEarned = 0
+	}
 
-	_, err = db.Exec(`
+	_, err = tx.Exec(`
 		INSERT INTO invoices (
 			sale_id, invoice_number, store_name, store_address, store_phone,
-			tax_percentage, tax_amount, subtotal, total_amount, payment_amount, change_amount
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			tax_percentage, tax_amount, subtotal, total_amount, payment_amount, change_amount,
+			customer_name, discount_amount, points_redeemed, points_payment, points_earned, points_balance,
+			payment_method
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
 		saleID, invoiceNumber, settings.StoreName, settings.StoreAddress, settings.StorePhone,
-		settings.TaxPercentage, taxAmount, subtotal, totalAmount, payment, change)
+		settings.TaxPercentage, totals.TaxAmount, subtotal, totals.Total, payment, change,
+		customerName, totals.Discount, totals.PointsRedeemed, totals.PointsPayment, totals.PointsEarned, pointsBalance,
+		paymentMethod)
+	if err != nil {
+		return InvoiceTotals{}, err
+	}
 
-	return err
+	return totals, tx.Commit()
 }
 
 func UpdateSettings(db *sql.DB, settings Settings) error {
@@ -222,6 +552,17 @@ func UpdateSettings(db *sql.DB, settings Settings) error {
 		"store_address":  settings.StoreAddress,
 		"store_phone":    settings.StorePhone,
 		"tax_percentage": fmt.Sprintf("%.2f", settings.TaxPercentage),
+
+		"loyalty_enabled":     strconv.FormatBool(settings.LoyaltyEnabled),
+		"loyalty_earn_amount": fmt.Sprintf("%.2f", settings.LoyaltyEarnAmount),
+		"loyalty_point_value": fmt.Sprintf("%.2f", settings.LoyaltyPointValue),
+		"loyalty_redeem_mode": settings.LoyaltyRedeemMode,
+
+		"supervisor_pin": settings.SupervisorPIN,
+
+		"expiry_lookahead_days": strconv.Itoa(settings.ExpiryLookaheadDays),
+
+		"time_zone": settings.TimeZone,
 	}
 
 	for key, value := range updates {
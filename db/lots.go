@@ -0,0 +1,244 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// getOpenLots returns a product's lots with stock remaining, first expiring
+// first. Lots without an expiry date come last.
+func getOpenLots(q queryer, productID int, lock bool) ([]types.StockLot, error) {
+	query := `
+		SELECT id, lot_number, expiry_date, quantity_received, quantity_remaining, received_at
+		FROM stock_lots
+		WHERE product_id = $1 AND quantity_remaining > 0
+		ORDER BY expiry_date NULLS LAST, received_at, id`
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	rows, err := q.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []types.StockLot
+	for rows.Next() {
+		var l types.StockLot
+		var expiry sql.NullTime
+		err := rows.Scan(&l.ID, &l.LotNumber, &expiry, &l.QuantityReceived, &l.QuantityRemaining, &l.ReceivedAt)
+		if err != nil {
+			return nil, err
+		}
+		if expiry.Valid {
+			l.ExpiryDate = &expiry.Time
+		}
+		l.Product.ID = productID
+		lots = append(lots, l)
+	}
+	return lots, rows.Err()
+}
+
+// GetExpiredQuantity returns how much of a product's stock is in expired lots
+// and can only be sold with a supervisor override
+func GetExpiredQuantity(db *sql.DB, productID int) (float64, error) {
+	lots, err := getOpenLots(db, productID, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired float64
+	today := time.Now()
+	for _, l := range lots {
+		if l.IsExpired(today) {
+			expired += l.QuantityRemaining
+		}
+	}
+	return expired, nil
+}
+
+// consumeLots draws a sold quantity from the product's lots, first expired
+// first out. Stock received without a lot is sold after the dated lots.
+// Expired lots only cover what fresh stock can't, and only once a supervisor
+// has allowed it.
+func consumeLots(tx *sql.Tx, saleItemID int, product types.Product, quantity float64, allowExpired bool) error {
+	lots, err := getOpenLots(tx, product.ID, true)
+	if err != nil {
+		return err
+	}
+	if len(lots) == 0 {
+		return nil
+	}
+
+	var stock, inLots float64
+	err = tx.QueryRow("SELECT stock FROM products WHERE id = $1 FOR UPDATE", product.ID).Scan(&stock)
+	if err != nil {
+		return err
+	}
+	today := time.Now()
+	var expired, fresh []types.StockLot
+	for _, l := range lots {
+		inLots += l.QuantityRemaining
+		if l.IsExpired(today) {
+			expired = append(expired, l)
+		} else {
+			fresh = append(fresh, l)
+		}
+	}
+	unlotted := math.Max(stock-inLots, 0)
+
+	draw := func(lots []types.StockLot, quantity float64) (float64, error) {
+		for _, l := range lots {
+			if quantity <= 0 {
+				break
+			}
+			take := math.Min(quantity, l.QuantityRemaining)
+			_, err := tx.Exec("UPDATE stock_lots SET quantity_remaining = quantity_remaining - $1 WHERE id = $2", take, l.ID)
+			if err != nil {
+				return 0, err
+			}
+			_, err = tx.Exec("INSERT INTO sale_item_lots (sale_item_id, lot_id, quantity) VALUES ($1, $2, $3)",
+				saleItemID, l.ID, take)
+			if err != nil {
+				return 0, err
+			}
+			quantity -= take
+		}
+		return quantity, nil
+	}
+
+	remaining, err := draw(fresh, quantity)
+	if err != nil {
+		return err
+	}
+	remaining -= math.Min(remaining, unlotted)
+	if remaining <= 0 || len(expired) == 0 {
+		return nil
+	}
+	if !allowExpired {
+		return fmt.Errorf("%s: only expired stock is left; a supervisor must approve the sale", product.Name)
+	}
+	_, err = draw(expired, remaining)
+	return err
+}
+
+// reduceLots keeps a product's lots within its stock when stock is written
+// down by an adjustment or count. Stock without a lot is written off first;
+// lots are drawn down, first expiring first, only for the rest.
+func reduceLots(tx *sql.Tx, productID int, quantity float64) error {
+	lots, err := getOpenLots(tx, productID, true)
+	if err != nil || len(lots) == 0 {
+		return err
+	}
+
+	var stock, inLots float64
+	err = tx.QueryRow("SELECT stock FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&stock)
+	if err != nil {
+		return err
+	}
+	for _, l := range lots {
+		inLots += l.QuantityRemaining
+	}
+
+	remaining := inLots - math.Max(stock-quantity, 0)
+	for _, l := range lots {
+		if remaining <= 0 {
+			break
+		}
+		take := math.Min(remaining, l.QuantityRemaining)
+		_, err = tx.Exec("UPDATE stock_lots SET quantity_remaining = quantity_remaining - $1 WHERE id = $2", take, l.ID)
+		if err != nil {
+			return err
+		}
+		remaining -= take
+	}
+	return nil
+}
+
+// returnLots puts refunded stock back into the lots the sale item was drawn
+// from, most recently expiring first. Any quantity sold from unlotted stock
+// goes back as unlotted stock.
+func returnLots(tx *sql.Tx, saleItemID, productID int, quantity float64) error {
+	rows, err := tx.Query(`
+		SELECT sil.lot_id, sil.quantity - sil.quantity_returned
+		FROM sale_item_lots sil
+		JOIN stock_lots l ON l.id = sil.lot_id
+		WHERE sil.sale_item_id = $1 AND l.product_id = $2 AND sil.quantity > sil.quantity_returned
+		ORDER BY l.expiry_date DESC NULLS FIRST, l.received_at DESC, l.id DESC
+		FOR UPDATE OF sil, l`, saleItemID, productID)
+	if err != nil {
+		return err
+	}
+	type draw struct {
+		lotID    int
+		quantity float64
+	}
+	var draws []draw
+	for rows.Next() {
+		var d draw
+		if err := rows.Scan(&d.lotID, &d.quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		draws = append(draws, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	remaining := quantity
+	for _, d := range draws {
+		if remaining <= 0 {
+			break
+		}
+		back := math.Min(remaining, d.quantity)
+		_, err = tx.Exec("UPDATE stock_lots SET quantity_remaining = quantity_remaining + $1 WHERE id = $2", back, d.lotID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE sale_item_lots SET quantity_returned = quantity_returned + $1
+			WHERE sale_item_id = $2 AND lot_id = $3`, back, saleItemID, d.lotID)
+		if err != nil {
+			return err
+		}
+		remaining -= back
+	}
+	return nil
+}
+
+// GetExpiringLots returns lots with stock remaining that expire within the
+// given number of days, including lots that have already expired
+func GetExpiringLots(db *sql.DB, days int) ([]types.StockLot, error) {
+	rows, err := db.Query(`
+		SELECT l.id, l.lot_number, l.expiry_date, l.quantity_received, l.quantity_remaining, l.received_at,
+			p.id, p.name, p.unit, p.quantity_precision, p.price, p.cost
+		FROM stock_lots l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.quantity_remaining > 0 AND l.expiry_date <= CURRENT_DATE + $1::integer
+		ORDER BY l.expiry_date, p.name`, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []types.StockLot
+	for rows.Next() {
+		var l types.StockLot
+		var expiry time.Time
+		err := rows.Scan(&l.ID, &l.LotNumber, &expiry, &l.QuantityReceived, &l.QuantityRemaining, &l.ReceivedAt,
+			&l.Product.ID, &l.Product.Name, &l.Product.Unit, &l.Product.Precision, &l.Product.Price, &l.Product.Cost)
+		if err != nil {
+			return nil, err
+		}
+		l.ExpiryDate = &expiry
+		lots = append(lots, l)
+	}
+	return lots, rows.Err()
+}
@@ -0,0 +1,14 @@
+DELETE FROM settings WHERE key IN ('loyalty_enabled', 'loyalty_earn_amount', 'loyalty_point_value', 'loyalty_redeem_mode');
+
+ALTER TABLE invoices
+    DROP COLUMN IF EXISTS customer_name,
+    DROP COLUMN IF EXISTS discount_amount,
+    DROP COLUMN IF EXISTS points_redeemed,
+    DROP COLUMN IF EXISTS points_payment,
+    DROP COLUMN IF EXISTS points_earned,
+    DROP COLUMN IF EXISTS points_balance;
+
+ALTER TABLE sales DROP COLUMN IF EXISTS customer_id;
+
+DROP TRIGGER IF EXISTS update_customers_updated_at ON customers;
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20) UNIQUE NOT NULL,
+    email VARCHAR(100),
+    points INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_customers_updated_at
+    BEFORE UPDATE ON customers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE sales ADD COLUMN customer_id INTEGER REFERENCES customers(id);
+
+ALTER TABLE invoices
+    ADD COLUMN customer_name VARCHAR(100),
+    ADD COLUMN discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN points_redeemed INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN points_payment DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN points_earned INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN points_balance INTEGER;
+
+-- Loyalty program settings
+INSERT INTO settings (key, value) VALUES
+    ('loyalty_enabled', 'true'),
+    ('loyalty_earn_amount', '10000'), -- spend required to earn one point
+    ('loyalty_point_value', '100'),   -- rupiah value of one point when redeemed
+    ('loyalty_redeem_mode', 'discount'); -- 'discount' (before tax) or 'payment' (after tax)
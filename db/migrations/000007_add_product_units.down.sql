@@ -0,0 +1,6 @@
+ALTER TABLE sale_items ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+
+ALTER TABLE products
+    ALTER COLUMN stock TYPE INTEGER USING ROUND(stock),
+    DROP COLUMN IF EXISTS quantity_precision,
+    DROP COLUMN IF EXISTS unit;
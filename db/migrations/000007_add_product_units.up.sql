@@ -0,0 +1,7 @@
+ALTER TABLE products
+    ADD COLUMN unit VARCHAR(20) NOT NULL DEFAULT 'pcs',
+    ADD COLUMN quantity_precision SMALLINT NOT NULL DEFAULT 0
+        CHECK (quantity_precision BETWEEN 0 AND 3),
+    ALTER COLUMN stock TYPE DECIMAL(12,3);
+
+ALTER TABLE sale_items ALTER COLUMN quantity TYPE DECIMAL(12,3);
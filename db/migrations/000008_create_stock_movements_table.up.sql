@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    movement_type VARCHAR(20) NOT NULL
+        CHECK (movement_type IN ('sale', 'refund', 'receipt', 'adjustment', 'count')),
+    quantity DECIMAL(12,3) NOT NULL, -- signed change, negative for stock leaving
+    stock_after DECIMAL(12,3) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    reference VARCHAR(100),
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_movements_product ON stock_movements (product_id, created_at);
+
+-- Opening balance: current stock plus everything already sold
+INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, username, note, created_at)
+SELECT p.id, 'adjustment', p.stock + COALESCE(SUM(si.quantity), 0), p.stock + COALESCE(SUM(si.quantity), 0),
+    'system', 'Opening balance', p.created_at
+FROM products p
+LEFT JOIN sale_items si ON si.product_id = p.id
+GROUP BY p.id;
+
+-- Replay existing sales so the ledger ends at the current stock
+INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, username, reference, created_at)
+SELECT si.product_id, 'sale', -si.quantity,
+    p.stock + SUM(si.quantity) OVER (PARTITION BY si.product_id)
+        - SUM(si.quantity) OVER (PARTITION BY si.product_id ORDER BY si.id),
+    'system', 'Sale #' || si.sale_id, si.created_at
+FROM sale_items si
+JOIN products p ON p.id = si.product_id;
@@ -0,0 +1,7 @@
+DELETE FROM settings WHERE key IN ('po_prefix', 'last_po_number');
+
+DROP TABLE IF EXISTS goods_receipt_lines;
+DROP TABLE IF EXISTS goods_receipts;
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TABLE IF EXISTS suppliers;
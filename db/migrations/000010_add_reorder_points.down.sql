@@ -0,0 +1,3 @@
+ALTER TABLE products
+    DROP COLUMN IF EXISTS reorder_point,
+    DROP COLUMN IF EXISTS reorder_quantity;
@@ -0,0 +1,8 @@
+ALTER TABLE products
+    ADD COLUMN reorder_point DECIMAL(12,3) NOT NULL DEFAULT 0,
+    ADD COLUMN reorder_quantity DECIMAL(12,3) NOT NULL DEFAULT 0;
+
+-- Sample reorder levels for the seeded products only; zero leaves reordering
+-- off for everything else
+UPDATE products SET reorder_point = 10, reorder_quantity = 50
+WHERE name IN ('Coffee', 'Tea', 'Milk');
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN sku VARCHAR(50) UNIQUE;
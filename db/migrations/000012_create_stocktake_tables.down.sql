@@ -0,0 +1,8 @@
+DELETE FROM settings WHERE key = 'supervisor_pin';
+
+DROP TABLE IF EXISTS stocktake_lines;
+DROP TABLE IF EXISTS stocktakes;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS shelf;
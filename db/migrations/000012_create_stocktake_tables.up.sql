@@ -0,0 +1,35 @@
+ALTER TABLE products
+    ADD COLUMN category VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN shelf VARCHAR(50) NOT NULL DEFAULT '';
+
+UPDATE products SET category = 'Beverages', shelf = 'A1' WHERE name IN ('Coffee', 'Tea');
+UPDATE products SET category = 'Dairy', shelf = 'C1' WHERE name = 'Milk';
+
+CREATE TABLE IF NOT EXISTS stocktakes (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'counting'
+        CHECK (status IN ('counting', 'posted', 'cancelled')),
+    category VARCHAR(50) NOT NULL DEFAULT '', -- empty means all categories
+    shelf VARCHAR(50) NOT NULL DEFAULT '',    -- empty means all shelves
+    created_by VARCHAR(50) NOT NULL,
+    approved_by VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    posted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS stocktake_lines (
+    id SERIAL PRIMARY KEY,
+    stocktake_id INTEGER NOT NULL REFERENCES stocktakes(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    expected_quantity DECIMAL(12,3) NOT NULL, -- stock frozen when the session started
+    counted_quantity DECIMAL(12,3),           -- NULL until counted
+    unit_value DECIMAL(10,2) NOT NULL,
+    counted_by VARCHAR(50),
+    counted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (stocktake_id, product_id)
+);
+
+-- PIN a supervisor enters to approve stock corrections
+INSERT INTO settings (key, value) VALUES
+    ('supervisor_pin', '1234');
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS sale_item_modifiers;
+DROP TABLE IF EXISTS product_modifier_groups;
+DROP TABLE IF EXISTS modifier_options;
+DROP TABLE IF EXISTS modifier_groups;
+
+DELETE FROM products WHERE parent_id IS NOT NULL;
+ALTER TABLE products
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS variant_name;
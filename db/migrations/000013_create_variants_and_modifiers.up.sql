@@ -0,0 +1,51 @@
+-- Variants are products of their own with a parent product that groups them
+ALTER TABLE products
+    ADD COLUMN parent_id INTEGER REFERENCES products(id),
+    ADD COLUMN variant_name VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS modifier_groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    min_select INTEGER NOT NULL DEFAULT 0,
+    max_select INTEGER NOT NULL DEFAULT 1,
+    CHECK (min_select >= 0 AND max_select >= 1 AND min_select <= max_select)
+);
+
+CREATE TABLE IF NOT EXISTS modifier_options (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES modifier_groups(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    price_delta DECIMAL(10,2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS product_modifier_groups (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    group_id INTEGER NOT NULL REFERENCES modifier_groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, group_id)
+);
+
+-- Snapshot of the modifiers chosen for a sale item
+CREATE TABLE IF NOT EXISTS sale_item_modifiers (
+    id SERIAL PRIMARY KEY,
+    sale_item_id INTEGER NOT NULL REFERENCES sale_items(id) ON DELETE CASCADE,
+    modifier_option_id INTEGER REFERENCES modifier_options(id) ON DELETE SET NULL,
+    name VARCHAR(50) NOT NULL,
+    price_delta DECIMAL(10,2) NOT NULL
+);
+
+-- Sample modifiers for hot drinks
+INSERT INTO modifier_groups (name, min_select, max_select) VALUES
+    ('Milk', 0, 1),
+    ('Extras', 0, 3);
+
+INSERT INTO modifier_options (group_id, name, price_delta) VALUES
+    ((SELECT id FROM modifier_groups WHERE name = 'Milk'), 'Oat milk', 5000),
+    ((SELECT id FROM modifier_groups WHERE name = 'Milk'), 'Soy milk', 4000),
+    ((SELECT id FROM modifier_groups WHERE name = 'Extras'), 'Extra shot', 5000),
+    ((SELECT id FROM modifier_groups WHERE name = 'Extras'), 'Vanilla syrup', 3000),
+    ((SELECT id FROM modifier_groups WHERE name = 'Extras'), 'Less sugar', 0);
+
+INSERT INTO product_modifier_groups (product_id, group_id)
+SELECT p.id, g.id
+FROM products p, modifier_groups g
+WHERE p.name IN ('Coffee', 'Tea');
@@ -0,0 +1,3 @@
+ALTER TABLE stocktake_lines ALTER COLUMN unit_value TYPE DECIMAL(10,2);
+ALTER TABLE sale_items DROP COLUMN IF EXISTS cost_at_sale;
+ALTER TABLE products DROP COLUMN IF EXISTS cost;
@@ -0,0 +1,17 @@
+-- Costs keep four decimals so weighted averages don't drift with rounding
+ALTER TABLE products
+    ADD COLUMN cost DECIMAL(12,4) NOT NULL DEFAULT 0;
+
+ALTER TABLE sale_items
+    ADD COLUMN cost_at_sale DECIMAL(12,4) NOT NULL DEFAULT 0;
+
+-- Stocktake variances are valued at cost
+ALTER TABLE stocktake_lines ALTER COLUMN unit_value TYPE DECIMAL(12,4);
+
+-- Sample products are bought in at roughly 60% of their selling price
+UPDATE products SET cost = ROUND(price * 0.6, 2);
+
+UPDATE sale_items si
+SET cost_at_sale = p.cost
+FROM products p
+WHERE p.id = si.product_id;
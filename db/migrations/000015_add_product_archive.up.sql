@@ -0,0 +1,3 @@
+-- Archived products are hidden from sale but kept for sales history
+ALTER TABLE products
+    ADD COLUMN active BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,17 @@
+-- Every selling price a product has had or is scheduled to have. The latest
+-- row that is already effective is the product's current price.
+CREATE TABLE IF NOT EXISTS product_prices (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price DECIMAL(10,2) NOT NULL,
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_prices_product ON product_prices (product_id, effective_from);
+
+-- Opening price for existing products
+INSERT INTO product_prices (product_id, price, effective_from, created_by)
+SELECT id, price, COALESCE(created_at, CURRENT_TIMESTAMP), 'system'
+FROM products;
@@ -0,0 +1,3 @@
+DELETE FROM settings WHERE key = 'expiry_lookahead_days';
+DROP TABLE IF EXISTS sale_item_lots;
+DROP TABLE IF EXISTS stock_lots;
@@ -0,0 +1,33 @@
+-- Lots record the lot number and expiry date of received stock. Sales draw
+-- down lots first-expired-first-out.
+CREATE TABLE IF NOT EXISTS stock_lots (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    lot_number VARCHAR(50) NOT NULL DEFAULT '',
+    expiry_date DATE,
+    quantity_received DECIMAL(12,3) NOT NULL,
+    quantity_remaining DECIMAL(12,3) NOT NULL,
+    goods_receipt_id INTEGER REFERENCES goods_receipts(id),
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (quantity_remaining >= 0)
+);
+
+CREATE INDEX idx_stock_lots_product ON stock_lots (product_id, expiry_date);
+
+-- Lots each sale item was drawn from, for traceability
+CREATE TABLE IF NOT EXISTS sale_item_lots (
+    sale_item_id INTEGER NOT NULL REFERENCES sale_items(id) ON DELETE CASCADE,
+    lot_id INTEGER NOT NULL REFERENCES stock_lots(id),
+    quantity DECIMAL(12,3) NOT NULL,
+    PRIMARY KEY (sale_item_id, lot_id)
+);
+
+-- Days ahead the expiring-soon report looks
+INSERT INTO settings (key, value) VALUES
+    ('expiry_lookahead_days', '7');
+
+-- Sample lots for the milk on hand, 28 after the sample sales
+INSERT INTO stock_lots (product_id, lot_number, expiry_date, quantity_received, quantity_remaining)
+SELECT id, 'MLK-0001', CURRENT_DATE + 3, 8, 8 FROM products WHERE name = 'Milk'
+UNION ALL
+SELECT id, 'MLK-0002', CURRENT_DATE + 14, 20, 20 FROM products WHERE name = 'Milk';
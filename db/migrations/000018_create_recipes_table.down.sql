@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS recipe_components;
+
+DELETE FROM stock_movements WHERE product_id IN (SELECT id FROM products WHERE sku IN ('BEAN-001', 'LATTE-001'));
+DELETE FROM product_prices WHERE product_id IN (SELECT id FROM products WHERE sku IN ('BEAN-001', 'LATTE-001'));
+DELETE FROM products WHERE sku IN ('BEAN-001', 'LATTE-001');
+UPDATE products SET unit = 'pcs', quantity_precision = 0 WHERE name = 'Milk';
+
+ALTER TABLE products DROP COLUMN IF EXISTS track_stock;
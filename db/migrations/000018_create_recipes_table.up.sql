@@ -0,0 +1,36 @@
+-- Products made to order, like a latte, don't hold stock of their own and
+-- only deduct the components of their recipe
+ALTER TABLE products
+    ADD COLUMN track_stock BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE IF NOT EXISTS recipe_components (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    component_id INTEGER NOT NULL REFERENCES products(id),
+    quantity DECIMAL(12,3) NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (product_id, component_id),
+    CHECK (product_id <> component_id)
+);
+
+-- Sample latte made from coffee beans and milk. Milk is measured in litres
+-- so a latte can use part of one.
+UPDATE products SET unit = 'L', quantity_precision = 2 WHERE name = 'Milk';
+
+INSERT INTO products (sku, name, price, stock, unit, quantity_precision, category, shelf, cost)
+VALUES ('BEAN-001', 'Coffee Beans', 250, 5000, 'g', 0, 'Beverages', 'A2', 150);
+
+INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, username, note)
+SELECT id, 'adjustment', stock, stock, 'system', 'Opening stock'
+FROM products WHERE sku = 'BEAN-001';
+
+INSERT INTO products (sku, name, price, stock, unit, quantity_precision, category, shelf, track_stock)
+VALUES ('LATTE-001', 'Latte', 25000, 0, 'pcs', 0, 'Beverages', '', FALSE);
+
+INSERT INTO recipe_components (product_id, component_id, quantity)
+SELECT latte.id, component.id, recipe.quantity
+FROM (VALUES ('BEAN-001', 18), ('Milk', 0.25)) AS recipe (component, quantity)
+JOIN products latte ON latte.sku = 'LATTE-001'
+JOIN products component ON component.sku = recipe.component OR component.name = recipe.component;
+
+INSERT INTO product_prices (product_id, price, effective_from, created_by)
+SELECT id, price, CURRENT_TIMESTAMP, 'system'
+FROM products WHERE sku IN ('BEAN-001', 'LATTE-001');
@@ -0,0 +1,7 @@
+ALTER TABLE sale_items
+    DROP COLUMN IF EXISTS package_id,
+    DROP COLUMN IF EXISTS package_name,
+    DROP COLUMN IF EXISTS package_quantity,
+    ALTER COLUMN price_at_sale TYPE DECIMAL(10,2);
+
+DROP TABLE IF EXISTS product_packages;
@@ -0,0 +1,23 @@
+-- Packaging units sell or receive a fixed number of a product's base unit,
+-- such as a carton of 24 pieces
+CREATE TABLE IF NOT EXISTS product_packages (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(30) NOT NULL,
+    barcode VARCHAR(50) UNIQUE,
+    units DECIMAL(12,3) NOT NULL CHECK (units > 0),
+    price DECIMAL(10,2) NOT NULL,
+    UNIQUE (product_id, name)
+);
+
+-- Sale items stay in base units. The package sold is kept for the receipt,
+-- and the unit price gets extra precision so package prices divide cleanly.
+ALTER TABLE sale_items
+    ADD COLUMN package_id INTEGER REFERENCES product_packages(id) ON DELETE SET NULL,
+    ADD COLUMN package_name VARCHAR(30) NOT NULL DEFAULT '',
+    ADD COLUMN package_quantity DECIMAL(12,3),
+    ALTER COLUMN price_at_sale TYPE DECIMAL(14,4);
+
+-- Sample carton of tea
+INSERT INTO product_packages (product_id, name, barcode, units, price)
+SELECT id, 'Carton', '8991234567890', 24, 220000 FROM products WHERE name = 'Tea';
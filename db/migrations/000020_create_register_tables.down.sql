@@ -0,0 +1,7 @@
+DELETE FROM settings WHERE key IN ('refund_prefix', 'last_refund_number', 'z_report_prefix', 'last_z_report_number');
+
+DROP TABLE IF EXISTS register_closures;
+DROP TABLE IF EXISTS refund_items;
+DROP TABLE IF EXISTS refunds;
+
+ALTER TABLE invoices DROP COLUMN IF EXISTS payment_method;
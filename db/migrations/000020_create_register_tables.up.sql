@@ -0,0 +1,47 @@
+ALTER TABLE invoices
+    ADD COLUMN payment_method VARCHAR(20) NOT NULL DEFAULT 'cash';
+
+-- Refunds return some or all of an invoice's items
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    refund_number VARCHAR(20) UNIQUE NOT NULL,
+    invoice_id INTEGER NOT NULL REFERENCES invoices(id),
+    amount DECIMAL(10,2) NOT NULL,
+    tax_amount DECIMAL(10,2) NOT NULL,
+    tax_percentage DECIMAL(5,2) NOT NULL,
+    payment_method VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    username VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS refund_items (
+    refund_id INTEGER NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    sale_item_id INTEGER NOT NULL REFERENCES sale_items(id),
+    quantity DECIMAL(12,3) NOT NULL CHECK (quantity > 0),
+    amount DECIMAL(10,2) NOT NULL,
+    PRIMARY KEY (refund_id, sale_item_id)
+);
+
+-- A Z-report closes the register for the period since the previous one
+CREATE TABLE IF NOT EXISTS register_closures (
+    id SERIAL PRIMARY KEY,
+    z_number VARCHAR(20) UNIQUE NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    gross_sales DECIMAL(12,2) NOT NULL,
+    discounts DECIMAL(12,2) NOT NULL,
+    refunds DECIMAL(12,2) NOT NULL,
+    net_sales DECIMAL(12,2) NOT NULL,
+    tax_collected DECIMAL(12,2) NOT NULL,
+    transaction_count INTEGER NOT NULL,
+    first_invoice VARCHAR(20),
+    last_invoice VARCHAR(20),
+    closed_by VARCHAR(50) NOT NULL
+);
+
+INSERT INTO settings (key, value) VALUES
+    ('refund_prefix', 'RF'),
+    ('last_refund_number', '0'),
+    ('z_report_prefix', 'Z'),
+    ('last_z_report_number', '0');
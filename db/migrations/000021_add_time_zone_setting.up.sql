@@ -0,0 +1,3 @@
+-- Reports group sales into days in the store's time zone
+INSERT INTO settings (key, value) VALUES
+    ('time_zone', 'Asia/Jakarta');
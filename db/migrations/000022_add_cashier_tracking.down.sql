@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS shifts;
+DROP TABLE IF EXISTS voids;
+ALTER TABLE sales DROP COLUMN IF EXISTS cashier;
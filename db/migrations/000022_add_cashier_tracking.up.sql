@@ -0,0 +1,25 @@
+-- Sales record the cashier who rang them up
+ALTER TABLE sales ADD COLUMN cashier VARCHAR(50) NOT NULL DEFAULT '';
+
+-- A void is a cart cleared before payment
+CREATE TABLE IF NOT EXISTS voids (
+    id SERIAL PRIMARY KEY,
+    cashier VARCHAR(50) NOT NULL,
+    item_count INTEGER NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A shift runs from the opening float to the cash count at close
+CREATE TABLE IF NOT EXISTS shifts (
+    id SERIAL PRIMARY KEY,
+    cashier VARCHAR(50) NOT NULL,
+    opening_float DECIMAL(12,2) NOT NULL,
+    opened_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expected_cash DECIMAL(12,2),
+    counted_cash DECIMAL(12,2),
+    closed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Only one open shift per cashier
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shifts_open ON shifts(cashier) WHERE closed_at IS NULL;
@@ -0,0 +1,7 @@
+-- Unit cost after each movement, so stock can be valued at any past date
+ALTER TABLE stock_movements ADD COLUMN cost_after DECIMAL(12,4);
+
+UPDATE stock_movements sm
+SET cost_after = p.cost
+FROM products p
+WHERE p.id = sm.product_id;
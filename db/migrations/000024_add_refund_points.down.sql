@@ -0,0 +1,4 @@
+ALTER TABLE refunds
+    DROP COLUMN IF EXISTS points_returned,
+    DROP COLUMN IF EXISTS points_payment,
+    DROP COLUMN IF EXISTS points_reversed;
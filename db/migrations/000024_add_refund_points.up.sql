@@ -0,0 +1,6 @@
+-- Loyalty points given back and taken back by a refund. points_payment is the
+-- value of returned points that had been used as payment.
+ALTER TABLE refunds
+    ADD COLUMN points_returned INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN points_payment DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN points_reversed INTEGER NOT NULL DEFAULT 0;
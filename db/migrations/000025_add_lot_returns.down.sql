@@ -0,0 +1 @@
+ALTER TABLE sale_item_lots DROP COLUMN IF EXISTS quantity_returned;
@@ -0,0 +1,3 @@
+-- Quantity of each lot draw put back by refunds
+ALTER TABLE sale_item_lots
+    ADD COLUMN quantity_returned DECIMAL(12,3) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE invoices ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE refunds ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
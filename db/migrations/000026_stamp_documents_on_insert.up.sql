@@ -0,0 +1,4 @@
+-- Invoices and refunds are stamped when the row is written rather than when
+-- its transaction began, so one held up by a register closure falls after it
+ALTER TABLE invoices ALTER COLUMN created_at SET DEFAULT clock_timestamp();
+ALTER TABLE refunds ALTER COLUMN created_at SET DEFAULT clock_timestamp();
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// AddVariant creates a variant of a product, such as a size or flavor. The
+// variant is a product of its own with its own SKU, price and stock, and
+// inherits the parent's unit, category and shelf.
+func AddVariant(db *sql.DB, parent types.Product, variant types.Product, username string) error {
+	if parent.ParentID != 0 {
+		return fmt.Errorf("%s is already a variant", parent.Name)
+	}
+	if variant.VariantName == "" {
+		return fmt.Errorf("variant name is required")
+	}
+
+	variant.ParentID = parent.ID
+	variant.Name = fmt.Sprintf("%s (%s)", parent.Name, variant.VariantName)
+	variant.Unit = parent.Unit
+	variant.Precision = parent.Precision
+	variant.Category = parent.Category
+	variant.Shelf = parent.Shelf
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := insertProduct(tx, variant, username); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func GetModifierGroups(db *sql.DB) ([]types.ModifierGroup, error) {
+	rows, err := db.Query(`
+		SELECT g.id, g.name, g.min_select, g.max_select, o.id, o.name, o.price_delta
+		FROM modifier_groups g
+		LEFT JOIN modifier_options o ON o.group_id = g.id
+		ORDER BY g.name, g.id, o.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []types.ModifierGroup
+	for rows.Next() {
+		var g types.ModifierGroup
+		var optionID sql.NullInt64
+		var optionName sql.NullString
+		var priceDelta sql.NullFloat64
+		err := rows.Scan(&g.ID, &g.Name, &g.MinSelect, &g.MaxSelect, &optionID, &optionName, &priceDelta)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(groups) == 0 || groups[len(groups)-1].ID != g.ID {
+			groups = append(groups, g)
+		}
+		if optionID.Valid {
+			last := &groups[len(groups)-1]
+			last.Options = append(last.Options, types.ModifierOption{
+				ID:         int(optionID.Int64),
+				GroupID:    g.ID,
+				Name:       optionName.String,
+				PriceDelta: priceDelta.Float64,
+			})
+		}
+	}
+	return groups, rows.Err()
+}
+
+// AddModifierGroup creates a modifier group together with its options
+func AddModifierGroup(db *sql.DB, group types.ModifierGroup) error {
+	if group.MaxSelect < 1 || group.MinSelect < 0 || group.MinSelect > group.MaxSelect {
+		return fmt.Errorf("invalid selection limits")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var groupID int
+	err = tx.QueryRow(`
+		INSERT INTO modifier_groups (name, min_select, max_select)
+		VALUES ($1, $2, $3)
+		RETURNING id`,
+		group.Name, group.MinSelect, group.MaxSelect).Scan(&groupID)
+	if err != nil {
+		return err
+	}
+
+	for _, option := range group.Options {
+		_, err = tx.Exec(`
+			INSERT INTO modifier_options (group_id, name, price_delta)
+			VALUES ($1, $2, $3)`,
+			groupID, option.Name, option.PriceDelta)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func DeleteModifierGroup(db *sql.DB, groupID int) error {
+	_, err := db.Exec("DELETE FROM modifier_groups WHERE id = $1", groupID)
+	return err
+}
+
+// GetProductModifierGroupIDs returns the modifier groups offered for each
+// product, keyed by product ID
+func GetProductModifierGroupIDs(db *sql.DB) (map[int][]int, error) {
+	rows, err := db.Query("SELECT product_id, group_id FROM product_modifier_groups ORDER BY product_id, group_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := map[int][]int{}
+	for rows.Next() {
+		var productID, groupID int
+		if err := rows.Scan(&productID, &groupID); err != nil {
+			return nil, err
+		}
+		groups[productID] = append(groups[productID], groupID)
+	}
+	return groups, rows.Err()
+}
+
+// SetProductModifierGroups replaces the modifier groups offered for a product
+func SetProductModifierGroups(db *sql.DB, productID int, groupIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM product_modifier_groups WHERE product_id = $1", productID)
+	if err != nil {
+		return err
+	}
+
+	for _, groupID := range groupIDs {
+		_, err = tx.Exec("INSERT INTO product_modifier_groups (product_id, group_id) VALUES ($1, $2)",
+			productID, groupID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
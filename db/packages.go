@@ -0,0 +1,77 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// GetPackages returns the packaging units of every product, keyed by product
+// ID and ordered from smallest to largest
+func GetPackages(db *sql.DB) (map[int][]types.ProductPackage, error) {
+	rows, err := db.Query(`
+		SELECT id, product_id, name, COALESCE(barcode, ''), units, price
+		FROM product_packages
+		ORDER BY product_id, units`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	packages := map[int][]types.ProductPackage{}
+	for rows.Next() {
+		var p types.ProductPackage
+		if err := rows.Scan(&p.ID, &p.ProductID, &p.Name, &p.Barcode, &p.Units, &p.Price); err != nil {
+			return nil, err
+		}
+		packages[p.ProductID] = append(packages[p.ProductID], p)
+	}
+	return packages, rows.Err()
+}
+
+func AddPackage(db *sql.DB, pkg types.ProductPackage) error {
+	if strings.TrimSpace(pkg.Name) == "" {
+		return fmt.Errorf("package name is required")
+	}
+	if pkg.Units <= 0 {
+		return fmt.Errorf("units per package must be more than zero")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO product_packages (product_id, name, barcode, units, price)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)`,
+		pkg.ProductID, pkg.Name, pkg.Barcode, pkg.Units, pkg.Price)
+	return err
+}
+
+func DeletePackage(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM product_packages WHERE id = $1", id)
+	return err
+}
+
+// FindByBarcode looks up a scanned code, first as a product SKU and then as a
+// package barcode. The package is nil when the code is a product SKU.
+func FindByBarcode(db *sql.DB, code string) (int, *types.ProductPackage, error) {
+	var productID int
+	err := db.QueryRow("SELECT id FROM products WHERE sku = $1 AND active", code).Scan(&productID)
+	if err == nil {
+		return productID, nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, nil, err
+	}
+
+	var pkg types.ProductPackage
+	err = db.QueryRow(`
+		SELECT pp.id, pp.product_id, pp.name, COALESCE(pp.barcode, ''), pp.units, pp.price
+		FROM product_packages pp
+		JOIN products p ON p.id = pp.product_id
+		WHERE pp.barcode = $1 AND p.active`, code).
+		Scan(&pkg.ID, &pkg.ProductID, &pkg.Name, &pkg.Barcode, &pkg.Units, &pkg.Price)
+	if err != nil {
+		return 0, nil, err
+	}
+	return pkg.ProductID, &pkg, nil
+}
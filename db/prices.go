@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// ErrPriceChanged is returned by SaveSale when a cart price no longer matches
+// the product's price, so the cart can be repriced and checked out again
+var ErrPriceChanged = errors.New("prices have changed since the items were added; please review the cart")
+
+// ApplyScheduledPrices brings every product's price up to date with the
+// latest price change that has taken effect
+func ApplyScheduledPrices(db *sql.DB) error {
+	return applyScheduledPrices(db)
+}
+
+// GetCurrentPrice applies any price changes that have come due and returns
+// the product's price in effect now
+func GetCurrentPrice(db *sql.DB, productID int) (float64, error) {
+	if err := applyScheduledPrices(db); err != nil {
+		return 0, err
+	}
+	return currentPrice(db, productID)
+}
+
+// currentPrice returns the latest price on the product's timeline that has
+// taken effect, falling back to the product's price
+func currentPrice(q queryer, productID int) (float64, error) {
+	var price float64
+	err := q.QueryRow(`
+		SELECT COALESCE((
+			SELECT price
+			FROM product_prices
+			WHERE product_id = $1 AND effective_from <= CURRENT_TIMESTAMP
+			ORDER BY effective_from DESC, id DESC
+			LIMIT 1
+		), p.price)
+		FROM products p
+		WHERE p.id = $1`, productID).Scan(&price)
+	return price, err
+}
+
+func applyScheduledPrices(q queryer) error {
+	_, err := q.Exec(`
+		UPDATE products p
+		SET price = due.price
+		FROM (
+			SELECT DISTINCT ON (product_id) product_id, price
+			FROM product_prices
+			WHERE effective_from <= CURRENT_TIMESTAMP
+			ORDER BY product_id, effective_from DESC, id DESC
+		) due
+		WHERE due.product_id = p.id AND p.price <> due.price`)
+	return err
+}
+
+// SchedulePriceChange adds a price change to a product's timeline. A change
+// that is already due is applied straight away.
+func SchedulePriceChange(db *sql.DB, productID int, price float64, effectiveFrom time.Time, username string) error {
+	if price < 0 {
+		return fmt.Errorf("price cannot be negative")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO product_prices (product_id, price, effective_from, created_by)
+		VALUES ($1, $2, $3, $4)`,
+		productID, price, effectiveFrom, username)
+	if err != nil {
+		return err
+	}
+
+	if !effectiveFrom.After(time.Now()) {
+		return ApplyScheduledPrices(db)
+	}
+	return nil
+}
+
+// CancelPriceChange removes a scheduled price change that has not yet taken
+// effect
+func CancelPriceChange(db *sql.DB, id int) error {
+	result, err := db.Exec("DELETE FROM product_prices WHERE id = $1 AND effective_from > CURRENT_TIMESTAMP", id)
+	if err != nil {
+		return err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return fmt.Errorf("price change has already taken effect")
+	}
+	return nil
+}
+
+// GetPriceHistory returns a product's price timeline, newest first
+func GetPriceHistory(db *sql.DB, productID int) ([]types.PriceChange, error) {
+	rows, err := db.Query(`
+		SELECT id, product_id, price, effective_from, created_by, created_at
+		FROM product_prices
+		WHERE product_id = $1
+		ORDER BY effective_from DESC, id DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []types.PriceChange
+	for rows.Next() {
+		var c types.PriceChange
+		err := rows.Scan(&c.ID, &c.ProductID, &c.Price, &c.EffectiveFrom, &c.CreatedBy, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,419 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// ProductCSVColumns are the columns written by ExportProducts and read by
+// PlanProductImport, so an export can be edited and imported again. A
+// variant's parent is given by its SKU, or by name when it has none.
+var ProductCSVColumns = []string{
+	"sku", "name", "price", "cost", "stock", "unit", "decimals", "reorder_point", "reorder_quantity",
+	"category", "shelf", "parent", "variant_name", "active", "track_stock",
+}
+
+// Import actions
+const (
+	ImportCreate = "create"
+	ImportUpdate = "update"
+	ImportError  = "error"
+)
+
+// ProductImportRow is the planned outcome of a single CSV row
+type ProductImportRow struct {
+	Line    int
+	Action  string
+	Product types.Product
+	// HasStock is set when the row carries a stock figure to apply
+	HasStock bool
+	// Parent is the SKU or name of the variant's parent, resolved when the
+	// import is applied so a parent created earlier in the file can be used
+	Parent string
+	Errors []string
+}
+
+// ProductImportPlan is a dry run of an import, applied with ApplyProductImport
+type ProductImportPlan struct {
+	Rows    []ProductImportRow
+	Creates int
+	Updates int
+	Errors  int
+}
+
+// ExportProducts writes every product, archived ones included, as CSV.
+// Parents come before their variants so the file imports in order. Products
+// made to order leave cost blank, since theirs is worked out from the recipe.
+func ExportProducts(db *sql.DB, w io.Writer) error {
+	products, err := GetAllProducts(db)
+	if err != nil {
+		return err
+	}
+	byID := map[int]types.Product{}
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		return products[i].ParentID == 0 && products[j].ParentID != 0
+	})
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ProductCSVColumns); err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		record := []string{
+			p.SKU,
+			p.Name,
+			strconv.FormatFloat(p.Price, 'f', 2, 64),
+			"",
+			types.FormatQuantity(p.Stock, p.Precision),
+			p.Unit,
+			strconv.Itoa(p.Precision),
+			types.FormatQuantity(p.ReorderPoint, p.Precision),
+			types.FormatQuantity(p.ReorderQuantity, p.Precision),
+			p.Category,
+			p.Shelf,
+			"",
+			p.VariantName,
+			strconv.FormatBool(p.Active),
+			strconv.FormatBool(p.TrackStock),
+		}
+		if p.TrackStock {
+			record[3] = strconv.FormatFloat(p.Cost, 'f', 4, 64)
+		}
+		if parent, ok := byID[p.ParentID]; ok {
+			record[11] = parent.SKU
+			if parent.SKU == "" {
+				record[11] = parent.Name
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// PlanProductImport validates every row of a product CSV and works out whether
+// it creates or updates a product. Rows match existing products by SKU, then
+// by name. Nothing is written to the database.
+func PlanProductImport(db *sql.DB, r io.Reader) (ProductImportPlan, error) {
+	var plan ProductImportPlan
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return plan, fmt.Errorf("could not read CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return plan, fmt.Errorf("CSV file is empty")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return plan, fmt.Errorf("CSV is missing the %q column", required)
+		}
+	}
+
+	existing, err := GetAllProducts(db)
+	if err != nil {
+		return plan, err
+	}
+	bySKU := map[string]types.Product{}
+	byName := map[string]types.Product{}
+	for _, p := range existing {
+		if p.SKU != "" {
+			bySKU[strings.ToLower(p.SKU)] = p
+		}
+		byName[strings.ToLower(p.Name)] = p
+	}
+
+	seenSKU := map[string]int{}
+	seenName := map[string]int{}
+	for i, record := range records[1:] {
+		line := i + 2
+		field := func(name string) string {
+			if idx, ok := columns[name]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+
+		row := parseProductRow(line, field)
+
+		// Match an existing product and keep its values for any blank columns
+		sku := strings.ToLower(row.Product.SKU)
+		name := strings.ToLower(row.Product.Name)
+		match, found := bySKU[sku]
+		if sku == "" || !found {
+			match, found = byName[name]
+			if found && sku != "" && match.SKU != "" {
+				row.Errors = append(row.Errors, fmt.Sprintf("name matches %s but SKU differs", match.SKU))
+			}
+		}
+		if found {
+			row.Action = ImportUpdate
+			row.Product.ID = match.ID
+			if !row.HasStock {
+				row.Product.Stock = match.Stock
+			}
+			if field("sku") == "" {
+				row.Product.SKU = match.SKU
+			}
+			if field("cost") == "" {
+				row.Product.Cost = match.Cost
+			}
+			if field("unit") == "" {
+				row.Product.Unit = match.Unit
+			}
+			if field("decimals") == "" {
+				row.Product.Precision = match.Precision
+			}
+			if field("reorder_point") == "" {
+				row.Product.ReorderPoint = match.ReorderPoint
+			}
+			if field("reorder_quantity") == "" {
+				row.Product.ReorderQuantity = match.ReorderQuantity
+			}
+			if field("category") == "" {
+				row.Product.Category = match.Category
+			}
+			if field("shelf") == "" {
+				row.Product.Shelf = match.Shelf
+			}
+			if field("variant_name") == "" {
+				row.Product.VariantName = match.VariantName
+			}
+			if field("active") == "" {
+				row.Product.Active = match.Active
+			}
+			if field("track_stock") == "" {
+				row.Product.TrackStock = match.TrackStock
+			}
+			if row.Parent == "" {
+				row.Product.ParentID = match.ParentID
+			}
+		} else {
+			row.Action = ImportCreate
+		}
+
+		// A parent must already exist or come earlier in the file
+		if parent := strings.ToLower(row.Parent); parent != "" {
+			_, existingSKU := bySKU[parent]
+			_, existingName := byName[parent]
+			_, earlierSKU := seenSKU[parent]
+			_, earlierName := seenName[parent]
+			switch {
+			case parent == sku || parent == name:
+				row.Errors = append(row.Errors, "product cannot be its own parent")
+			case !existingSKU && !existingName && !earlierSKU && !earlierName:
+				row.Errors = append(row.Errors, fmt.Sprintf("parent %q not found", row.Parent))
+			}
+		}
+		row.Product.Stock = row.Product.RoundQuantity(row.Product.Stock)
+		row.Product.ReorderPoint = row.Product.RoundQuantity(row.Product.ReorderPoint)
+		row.Product.ReorderQuantity = row.Product.RoundQuantity(row.Product.ReorderQuantity)
+
+		// The same product must not appear twice in one file
+		if sku != "" {
+			if first, dup := seenSKU[sku]; dup {
+				row.Errors = append(row.Errors, fmt.Sprintf("SKU already used on line %d", first))
+			}
+			seenSKU[sku] = line
+		}
+		if name != "" {
+			if first, dup := seenName[name]; dup {
+				row.Errors = append(row.Errors, fmt.Sprintf("name already used on line %d", first))
+			}
+			seenName[name] = line
+		}
+
+		if len(row.Errors) > 0 {
+			row.Action = ImportError
+			plan.Errors++
+		} else if row.Action == ImportCreate {
+			plan.Creates++
+		} else {
+			plan.Updates++
+		}
+		plan.Rows = append(plan.Rows, row)
+	}
+
+	return plan, nil
+}
+
+func parseProductRow(line int, field func(string) string) ProductImportRow {
+	row := ProductImportRow{Line: line}
+	p := &row.Product
+
+	p.SKU = field("sku")
+	p.Name = field("name")
+	if p.Name == "" {
+		row.Errors = append(row.Errors, "name is required")
+	}
+
+	price, err := strconv.ParseFloat(field("price"), 64)
+	if err != nil || price < 0 {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid price %q", field("price")))
+	}
+	p.Price = price
+
+	if text := field("cost"); text != "" {
+		p.Cost, err = strconv.ParseFloat(text, 64)
+		if err != nil || p.Cost < 0 {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid cost %q", text))
+		}
+	}
+
+	p.Category = field("category")
+	p.Shelf = field("shelf")
+	p.VariantName = field("variant_name")
+	row.Parent = field("parent")
+
+	p.Active = true
+	if text := field("active"); text != "" {
+		p.Active, err = strconv.ParseBool(text)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid active %q", text))
+		}
+	}
+	p.TrackStock = true
+	if text := field("track_stock"); text != "" {
+		p.TrackStock, err = strconv.ParseBool(text)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid track_stock %q", text))
+		}
+	}
+
+	p.Unit = field("unit")
+	if p.Unit == "" {
+		p.Unit = "pcs"
+	}
+
+	if text := field("decimals"); text != "" {
+		p.Precision, err = strconv.Atoi(text)
+		if err != nil || p.Precision < 0 || p.Precision > 3 {
+			row.Errors = append(row.Errors, "decimals must be between 0 and 3")
+		}
+	}
+
+	parseQuantity := func(column string) (float64, bool) {
+		text := field(column)
+		if text == "" {
+			return 0, false
+		}
+		quantity, err := strconv.ParseFloat(text, 64)
+		if err != nil || quantity < 0 {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid %s %q", column, text))
+			return 0, false
+		}
+		return quantity, true
+	}
+	p.Stock, row.HasStock = parseQuantity("stock")
+	p.ReorderPoint, _ = parseQuantity("reorder_point")
+	p.ReorderQuantity, _ = parseQuantity("reorder_quantity")
+
+	return row
+}
+
+// ApplyProductImport writes a validated import plan in a single transaction.
+// Stock on updated products is set through the ledger as a count.
+func ApplyProductImport(db *sql.DB, plan ProductImportPlan, username string) error {
+	if plan.Errors > 0 {
+		return fmt.Errorf("import has %d invalid rows", plan.Errors)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, row := range plan.Rows {
+		if row.Parent != "" {
+			err := tx.QueryRow(`
+				SELECT id FROM products
+				WHERE LOWER(sku) = LOWER($1) OR LOWER(name) = LOWER($1)
+				ORDER BY (LOWER(sku) = LOWER($1)) IS TRUE DESC
+				LIMIT 1`, row.Parent).Scan(&row.Product.ParentID)
+			if err != nil {
+				return fmt.Errorf("line %d: parent %q: %v", row.Line, row.Parent, err)
+			}
+		}
+
+		switch row.Action {
+		case ImportCreate:
+			id, err := insertProduct(tx, row.Product, username)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", row.Line, err)
+			}
+			if err := setImportedDetails(tx, id, row.Product); err != nil {
+				return fmt.Errorf("line %d: %v", row.Line, err)
+			}
+		case ImportUpdate:
+			if err := updateProduct(tx, row.Product, username); err != nil {
+				return fmt.Errorf("line %d: %v", row.Line, err)
+			}
+			if err := setImportedDetails(tx, row.Product.ID, row.Product); err != nil {
+				return fmt.Errorf("line %d: %v", row.Line, err)
+			}
+			if !row.HasStock {
+				continue
+			}
+
+			var current float64
+			err := tx.QueryRow("SELECT stock FROM products WHERE id = $1 FOR UPDATE", row.Product.ID).Scan(&current)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", row.Line, err)
+			}
+			if current == row.Product.Stock {
+				continue
+			}
+			if row.Product.Stock < current {
+				if err := reduceLots(tx, row.Product.ID, current-row.Product.Stock); err != nil {
+					return fmt.Errorf("line %d: %v", row.Line, err)
+				}
+			}
+			err = RecordStockMovement(tx, types.StockMovement{
+				ProductID: row.Product.ID,
+				Type:      types.MovementCount,
+				Quantity:  row.Product.Stock - current,
+				Username:  username,
+				Note:      "CSV import",
+			})
+			if err != nil {
+				return fmt.Errorf("line %d: %v", row.Line, err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// setImportedDetails applies the parent, variant name, archived state and
+// whether stock is held from an imported row, which the product editor
+// doesn't change
+func setImportedDetails(tx *sql.Tx, id int, product types.Product) error {
+	_, err := tx.Exec(`
+		UPDATE products
+		SET parent_id = NULLIF($1, 0), variant_name = $2, active = $3, track_stock = $4
+		WHERE id = $5`,
+		product.ParentID, product.VariantName, product.Active, product.TrackStock, id)
+	return err
+}
@@ -0,0 +1,322 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+func GetSuppliers(db *sql.DB) ([]types.Supplier, error) {
+	rows, err := db.Query(`
+		SELECT id, name, COALESCE(contact_name, ''), COALESCE(phone, ''),
+			COALESCE(email, ''), COALESCE(address, '')
+		FROM suppliers
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppliers []types.Supplier
+	for rows.Next() {
+		var s types.Supplier
+		if err := rows.Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.Address); err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, s)
+	}
+	return suppliers, nil
+}
+
+func AddSupplier(db *sql.DB, supplier types.Supplier) error {
+	_, err := db.Exec(`
+		INSERT INTO suppliers (name, contact_name, phone, email, address)
+		VALUES ($1, $2, $3, $4, $5)`,
+		supplier.Name, supplier.ContactName, supplier.Phone, supplier.Email, supplier.Address)
+	return err
+}
+
+func UpdateSupplier(db *sql.DB, supplier types.Supplier) error {
+	_, err := db.Exec(`
+		UPDATE suppliers
+		SET name = $1, contact_name = $2, phone = $3, email = $4, address = $5
+		WHERE id = $6`,
+		supplier.Name, supplier.ContactName, supplier.Phone, supplier.Email, supplier.Address, supplier.ID)
+	return err
+}
+
+// nextDocumentNumber increments a numbering counter kept in settings and
+// returns the formatted document number, e.g. PO000012
+func nextDocumentNumber(tx *sql.Tx, prefixKey, counterKey string) (string, error) {
+	var prefix string
+	var lastNum int
+	err := tx.QueryRow("SELECT value FROM settings WHERE key = $1", prefixKey).Scan(&prefix)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.QueryRow("SELECT CAST(value AS INTEGER) FROM settings WHERE key = $1 FOR UPDATE", counterKey).Scan(&lastNum)
+	if err != nil {
+		return "", err
+	}
+
+	newNum := lastNum + 1
+	_, err = tx.Exec("UPDATE settings SET value = $1, updated_at = CURRENT_TIMESTAMP WHERE key = $2",
+		strconv.Itoa(newNum), counterKey)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%06d", prefix, newNum), nil
+}
+
+// CreatePurchaseOrder saves a new purchase order with its lines and returns
+// the generated PO number
+func CreatePurchaseOrder(db *sql.DB, po types.PurchaseOrder, username string) (string, error) {
+	if len(po.Lines) == 0 {
+		return "", fmt.Errorf("purchase order has no lines")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	number, err := nextDocumentNumber(tx, "po_prefix", "last_po_number")
+	if err != nil {
+		return "", err
+	}
+
+	var poID int
+	err = tx.QueryRow(`
+		INSERT INTO purchase_orders (po_number, supplier_id, expected_date, note, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING id`,
+		number, po.SupplierID, po.ExpectedDate, po.Note, username).Scan(&poID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range po.Lines {
+		_, err = tx.Exec(`
+			INSERT INTO purchase_order_lines (purchase_order_id, product_id, quantity_ordered, unit_cost)
+			VALUES ($1, $2, $3, $4)`,
+			poID, line.Product.ID, line.QuantityOrdered, line.UnitCost)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return number, tx.Commit()
+}
+
+// GetPurchaseOrders returns purchase orders with their lines, newest first.
+// When openOnly is set, only orders still awaiting delivery are returned.
+func GetPurchaseOrders(db *sql.DB, openOnly bool) ([]types.PurchaseOrder, error) {
+	rows, err := db.Query(`
+		SELECT po.id, po.po_number, po.supplier_id, s.name, po.status, po.expected_date,
+			COALESCE(po.note, ''), po.created_by, po.created_at
+		FROM purchase_orders po
+		JOIN suppliers s ON s.id = po.supplier_id
+		WHERE NOT $1 OR po.status IN ('open', 'partial')
+		ORDER BY po.created_at DESC`, openOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []types.PurchaseOrder
+	index := map[int]int{}
+	for rows.Next() {
+		var po types.PurchaseOrder
+		var expected sql.NullTime
+		err := rows.Scan(&po.ID, &po.Number, &po.SupplierID, &po.SupplierName, &po.Status, &expected,
+			&po.Note, &po.CreatedBy, &po.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if expected.Valid {
+			po.ExpectedDate = &expected.Time
+		}
+		index[po.ID] = len(orders)
+		orders = append(orders, po)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lineRows, err := db.Query(`
+		SELECT l.id, l.purchase_order_id, l.quantity_ordered, l.quantity_received, l.unit_cost,
+			p.id, p.name, p.price, p.stock, p.unit, p.quantity_precision
+		FROM purchase_order_lines l
+		JOIN purchase_orders po ON po.id = l.purchase_order_id
+		JOIN products p ON p.id = l.product_id
+		WHERE NOT $1 OR po.status IN ('open', 'partial')
+		ORDER BY l.id`, openOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var l types.PurchaseOrderLine
+		var poID int
+		err := lineRows.Scan(&l.ID, &poID, &l.QuantityOrdered, &l.QuantityReceived, &l.UnitCost,
+			&l.Product.ID, &l.Product.Name, &l.Product.Price, &l.Product.Stock, &l.Product.Unit, &l.Product.Precision)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[poID]; ok {
+			orders[i].Lines = append(orders[i].Lines, l)
+		}
+	}
+	return orders, lineRows.Err()
+}
+
+// ReceiveGoods records a full or partial delivery against a purchase order
+// and adds the delivered quantities to stock. A line receiving more than is
+// still outstanding rejects the whole delivery.
+func ReceiveGoods(db *sql.DB, po types.PurchaseOrder, lines []types.ReceiptLine, username, note string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM purchase_orders WHERE id = $1 FOR UPDATE", po.ID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != types.POStatusOpen && status != types.POStatusPartial {
+		return fmt.Errorf("purchase order %s is %s", po.Number, status)
+	}
+
+	var receiptID int
+	err = tx.QueryRow(`
+		INSERT INTO goods_receipts (purchase_order_id, received_by, note)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id`,
+		po.ID, username, note).Scan(&receiptID)
+	if err != nil {
+		return err
+	}
+
+	received := false
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			continue
+		}
+
+		// A line can't receive more than was ordered
+		var productID int
+		err = tx.QueryRow(`
+			UPDATE purchase_order_lines
+			SET quantity_received = quantity_received + $1
+			WHERE id = $2 AND purchase_order_id = $3 AND quantity_received + $1 <= quantity_ordered
+			RETURNING product_id`,
+			line.Quantity, line.PurchaseOrderLineID, po.ID).Scan(&productID)
+		if err == sql.ErrNoRows {
+			return overReceiptError(tx, line)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO goods_receipt_lines (goods_receipt_id, purchase_order_line_id, product_id, quantity, unit_cost)
+			VALUES ($1, $2, $3, $4, $5)`,
+			receiptID, line.PurchaseOrderLineID, productID, line.Quantity, line.UnitCost)
+		if err != nil {
+			return err
+		}
+
+		if err := updateAverageCost(tx, productID, line.Quantity, line.UnitCost); err != nil {
+			return err
+		}
+
+		if line.LotNumber != "" || line.ExpiryDate != nil {
+			_, err = tx.Exec(`
+				INSERT INTO stock_lots (product_id, lot_number, expiry_date, quantity_received, quantity_remaining,
+					goods_receipt_id)
+				VALUES ($1, $2, $3, $4, $4, $5)`,
+				productID, line.LotNumber, line.ExpiryDate, line.Quantity, receiptID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = RecordStockMovement(tx, types.StockMovement{
+			ProductID: productID,
+			Type:      types.MovementReceipt,
+			Quantity:  line.Quantity,
+			Username:  username,
+			Reference: po.Number,
+			Note:      note,
+		})
+		if err != nil {
+			return err
+		}
+		received = true
+	}
+	if !received {
+		return fmt.Errorf("no quantities received")
+	}
+
+	// The order is complete once nothing is outstanding on any line
+	_, err = tx.Exec(`
+		UPDATE purchase_orders
+		SET status = CASE
+			WHEN EXISTS (
+				SELECT 1 FROM purchase_order_lines
+				WHERE purchase_order_id = $1 AND quantity_received < quantity_ordered
+			) THEN 'partial'
+			ELSE 'received'
+		END
+		WHERE id = $1`, po.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateAverageCost blends the cost of received goods into the product's
+// weighted-average cost. Negative stock is treated as none on hand, so the
+// received cost is used as is.
+func updateAverageCost(q queryer, productID int, quantity, unitCost float64) error {
+	_, err := q.Exec(`
+		UPDATE products
+		SET cost = ROUND((GREATEST(stock, 0) * cost + $1::numeric * $2::numeric) / (GREATEST(stock, 0) + $1::numeric), 4)
+		WHERE id = $3`,
+		quantity, unitCost, productID)
+	return err
+}
+
+// overReceiptError explains why a receipt line was rejected, naming the
+// quantity still outstanding
+func overReceiptError(tx *sql.Tx, line types.ReceiptLine) error {
+	var name string
+	var outstanding float64
+	var precision int
+	err := tx.QueryRow(`
+		SELECT p.name, l.quantity_ordered - l.quantity_received, p.quantity_precision
+		FROM purchase_order_lines l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.id = $1`, line.PurchaseOrderLineID).Scan(&name, &outstanding, &precision)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s: only %s is still to be received", name, types.FormatQuantity(outstanding, precision))
+}
+
+func CancelPurchaseOrder(db *sql.DB, poID int) error {
+	_, err := db.Exec(`
+		UPDATE purchase_orders
+		SET status = 'cancelled'
+		WHERE id = $1 AND status IN ('open', 'partial')`, poID)
+	return err
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+func getRecipe(q queryer, productID int) ([]types.RecipeComponent, error) {
+	rows, err := q.Query(`
+		SELECT rc.quantity, p.id, p.name, p.unit, p.quantity_precision, p.stock, p.cost, p.price
+		FROM recipe_components rc
+		JOIN products p ON p.id = rc.component_id
+		WHERE rc.product_id = $1
+		ORDER BY p.name`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var components []types.RecipeComponent
+	for rows.Next() {
+		var c types.RecipeComponent
+		err := rows.Scan(&c.Quantity, &c.Component.ID, &c.Component.Name, &c.Component.Unit,
+			&c.Component.Precision, &c.Component.Stock, &c.Component.Cost, &c.Component.Price)
+		if err != nil {
+			return nil, err
+		}
+		c.Component.TrackStock = true
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// GetRecipe returns the components used to make one unit of a product
+func GetRecipe(db *sql.DB, productID int) ([]types.RecipeComponent, error) {
+	return getRecipe(db, productID)
+}
+
+// GetRecipeQuantities returns, for every composite product, the quantity of
+// each component per unit keyed by component product ID
+func GetRecipeQuantities(db *sql.DB) (map[int]map[int]float64, error) {
+	rows, err := db.Query("SELECT product_id, component_id, quantity FROM recipe_components")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipes := map[int]map[int]float64{}
+	for rows.Next() {
+		var productID, componentID int
+		var quantity float64
+		if err := rows.Scan(&productID, &componentID, &quantity); err != nil {
+			return nil, err
+		}
+		if recipes[productID] == nil {
+			recipes[productID] = map[int]float64{}
+		}
+		recipes[productID][componentID] = quantity
+	}
+	return recipes, rows.Err()
+}
+
+// SetRecipe replaces a product's recipe. A product that doesn't track its own
+// stock must have at least one component, since selling it would otherwise
+// deduct nothing.
+func SetRecipe(db *sql.DB, productID int, components []types.RecipeComponent, trackStock bool) error {
+	if !trackStock && len(components) == 0 {
+		return fmt.Errorf("a product without its own stock needs at least one component")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM recipe_components WHERE product_id = $1", productID)
+	if err != nil {
+		return err
+	}
+
+	if len(components) > 0 {
+		var usedAsComponent bool
+		err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM recipe_components WHERE component_id = $1)", productID).
+			Scan(&usedAsComponent)
+		if err != nil {
+			return err
+		}
+		if usedAsComponent {
+			return fmt.Errorf("product is a component of another recipe and cannot have one of its own")
+		}
+	}
+
+	for _, c := range components {
+		if c.Component.ID == productID {
+			return fmt.Errorf("a product cannot be a component of itself")
+		}
+		if c.Quantity <= 0 {
+			return fmt.Errorf("invalid quantity for %s", c.Component.Name)
+		}
+
+		// Only one level of recipes is supported
+		var nested bool
+		err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM recipe_components WHERE product_id = $1)", c.Component.ID).
+			Scan(&nested)
+		if err != nil {
+			return err
+		}
+		if nested {
+			return fmt.Errorf("%s has a recipe of its own and cannot be a component", c.Component.Name)
+		}
+
+		_, err = tx.Exec("INSERT INTO recipe_components (product_id, component_id, quantity) VALUES ($1, $2, $3)",
+			productID, c.Component.ID, c.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE products SET track_stock = $1 WHERE id = $2", trackStock, productID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
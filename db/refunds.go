@@ -0,0 +1,230 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// RefundableInvoice is an invoice looked up for a refund with the items that
+// can still be returned
+type RefundableInvoice struct {
+	ID            int
+	Number        string
+	PaymentMethod string
+	Subtotal      float64
+	Total         float64
+	TaxAmount     float64
+	TaxPercentage float64
+	CreatedAt     sql.NullTime
+	Lines         []types.RefundLine
+}
+
+// GetRefundableInvoice loads an invoice by number with the quantity of each
+// item sold and already refunded
+func GetRefundableInvoice(db *sql.DB, invoiceNumber string) (RefundableInvoice, error) {
+	var inv RefundableInvoice
+	var saleID int
+	err := db.QueryRow(`
+		SELECT id, invoice_number, sale_id, payment_method, COALESCE(subtotal, 0), COALESCE(total_amount, 0),
+			COALESCE(tax_amount, 0), COALESCE(tax_percentage, 0), created_at
+		FROM invoices
+		WHERE invoice_number = $1`, invoiceNumber).
+		Scan(&inv.ID, &inv.Number, &saleID, &inv.PaymentMethod, &inv.Subtotal, &inv.Total,
+			&inv.TaxAmount, &inv.TaxPercentage, &inv.CreatedAt)
+	if err != nil {
+		return inv, err
+	}
+
+	rows, err := db.Query(`
+		SELECT si.id, si.quantity, si.price_at_sale,
+			COALESCE((SELECT SUM(ri.quantity) FROM refund_items ri WHERE ri.sale_item_id = si.id), 0),
+			p.id, p.name, p.unit, p.quantity_precision
+		FROM sale_items si
+		JOIN products p ON p.id = si.product_id
+		WHERE si.sale_id = $1
+		ORDER BY si.id`, saleID)
+	if err != nil {
+		return inv, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l types.RefundLine
+		err := rows.Scan(&l.SaleItemID, &l.QuantitySold, &l.UnitPrice, &l.QuantityRefunded,
+			&l.Product.ID, &l.Product.Name, &l.Product.Unit, &l.Product.Precision)
+		if err != nil {
+			return inv, err
+		}
+		inv.Lines = append(inv.Lines, l)
+	}
+	return inv, rows.Err()
+}
+
+// RefundTotals holds the amounts returned by a refund
+type RefundTotals struct {
+	Number         string
+	Amount         float64
+	TaxAmount      float64
+	PointsReturned int
+	PointsPayment  float64
+	PointsReversed int
+}
+
+// CreateRefund refunds the given quantities of an invoice's items. Each line
+// is refunded at its share of the amount the customer paid in money, through
+// the invoice's payment method, so discounts and tax are returned in
+// proportion. Points redeemed on the invoice are given back and points earned
+// are taken back in the same proportion. Products that hold stock are put
+// back on hand and into the lots they were sold from.
+func CreateRefund(db *sql.DB, inv RefundableInvoice, lines []types.RefundLine, reason, username string) (RefundTotals, error) {
+	var totals RefundTotals
+	tx, err := db.Begin()
+	if err != nil {
+		return totals, err
+	}
+	defer tx.Rollback()
+
+	// Lock the invoice so refunds against it are worked out one at a time
+	var subtotal, total, tax, pointsPayment, refundedGross float64
+	var pointsRedeemed, pointsEarned, pointsReturned, pointsReversed int
+	var customerID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT COALESCE(i.subtotal, 0), COALESCE(i.total_amount, 0), COALESCE(i.tax_amount, 0),
+			i.points_redeemed, i.points_payment, i.points_earned, s.customer_id
+		FROM invoices i
+		JOIN sales s ON s.id = i.sale_id
+		WHERE i.id = $1
+		FOR UPDATE OF i`, inv.ID).
+		Scan(&subtotal, &total, &tax, &pointsRedeemed, &pointsPayment, &pointsEarned, &customerID)
+	if err != nil {
+		return totals, err
+	}
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(ri.quantity * si.price_at_sale), 0),
+			COALESCE((SELECT SUM(points_returned) FROM refunds WHERE invoice_id = $1), 0),
+			COALESCE((SELECT SUM(points_reversed) FROM refunds WHERE invoice_id = $1), 0)
+		FROM refund_items ri
+		JOIN refunds r ON r.id = ri.refund_id
+		JOIN sale_items si ON si.id = ri.sale_item_id
+		WHERE r.invoice_id = $1`, inv.ID).
+		Scan(&refundedGross, &pointsReturned, &pointsReversed)
+	if err != nil {
+		return totals, err
+	}
+
+	totals.Number, err = nextDocumentNumber(tx, "refund_prefix", "last_refund_number")
+	if err != nil {
+		return totals, err
+	}
+
+	// Points used as payment are given back as points, not money
+	ratio := 0.0
+	if subtotal > 0 {
+		ratio = (total - pointsPayment) / subtotal
+	}
+
+	var refundID int
+	err = tx.QueryRow(`
+		INSERT INTO refunds (refund_number, invoice_id, amount, tax_amount, tax_percentage, payment_method, reason, username)
+		VALUES ($1, $2, 0, 0, $3, $4, $5, $6)
+		RETURNING id`,
+		totals.Number, inv.ID, inv.TaxPercentage, inv.PaymentMethod, reason, username).Scan(&refundID)
+	if err != nil {
+		return totals, err
+	}
+
+	var gross float64
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			continue
+		}
+
+		// Lock the sale item so two refunds can't return the same goods
+		var sold, refunded, price float64
+		var productID int
+		var trackStock bool
+		err = tx.QueryRow(`
+			SELECT si.quantity, si.price_at_sale, si.product_id, p.track_stock,
+				COALESCE((SELECT SUM(ri.quantity) FROM refund_items ri WHERE ri.sale_item_id = si.id), 0)
+			FROM sale_items si
+			JOIN products p ON p.id = si.product_id
+			WHERE si.id = $1
+			FOR UPDATE OF si`, line.SaleItemID).
+			Scan(&sold, &price, &productID, &trackStock, &refunded)
+		if err != nil {
+			return totals, err
+		}
+		if line.Quantity > sold-refunded {
+			return totals, fmt.Errorf("only %s of %s can still be refunded",
+				line.Product.FormatQuantity(sold-refunded), line.Product.Name)
+		}
+
+		amount := math.Round(line.Quantity*price*ratio*100) / 100
+		_, err = tx.Exec("INSERT INTO refund_items (refund_id, sale_item_id, quantity, amount) VALUES ($1, $2, $3, $4)",
+			refundID, line.SaleItemID, line.Quantity, amount)
+		if err != nil {
+			return totals, err
+		}
+		totals.Amount += amount
+		gross += line.Quantity * price
+
+		if trackStock {
+			if err := returnLots(tx, line.SaleItemID, productID, line.Quantity); err != nil {
+				return totals, err
+			}
+			err = RecordStockMovement(tx, types.StockMovement{
+				ProductID: productID,
+				Type:      types.MovementRefund,
+				Quantity:  line.Quantity,
+				Username:  username,
+				Reference: totals.Number,
+				Note:      inv.Number,
+			})
+			if err != nil {
+				return totals, err
+			}
+		}
+	}
+	if gross == 0 || subtotal == 0 {
+		return totals, fmt.Errorf("nothing selected to refund")
+	}
+
+	// Points follow the share of the invoice refunded so far, so repeated
+	// partial refunds add up to the invoice's points without rounding drift
+	share := math.Min((refundedGross+gross)/subtotal, 1)
+	totals.PointsReturned = int(math.Round(float64(pointsRedeemed)*share)) - pointsReturned
+	totals.PointsReversed = int(math.Round(float64(pointsEarned)*share)) - pointsReversed
+	if pointsRedeemed > 0 {
+		totals.PointsPayment = math.Round(pointsPayment*float64(totals.PointsReturned)/float64(pointsRedeemed)*100) / 100
+	}
+	totals.TaxAmount = math.Round(gross/subtotal*tax*100) / 100
+
+	if customerID.Valid && (totals.PointsReturned != 0 || totals.PointsReversed != 0) {
+		var balance int
+		err = tx.QueryRow("SELECT points FROM customers WHERE id = $1 FOR UPDATE", customerID.Int64).Scan(&balance)
+		if err != nil {
+			return totals, err
+		}
+		// Earned points the customer has already spent can't be taken back
+		totals.PointsReversed = min(totals.PointsReversed, balance+totals.PointsReturned)
+		_, err = tx.Exec("UPDATE customers SET points = $1 WHERE id = $2",
+			balance+totals.PointsReturned-totals.PointsReversed, customerID.Int64)
+		if err != nil {
+			return totals, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE refunds
+		SET amount = $1, tax_amount = $2, points_returned = $3, points_payment = $4, points_reversed = $5
+		WHERE id = $6`,
+		totals.Amount, totals.TaxAmount, totals.PointsReturned, totals.PointsPayment, totals.PointsReversed, refundID)
+	if err != nil {
+		return totals, err
+	}
+
+	return totals, tx.Commit()
+}
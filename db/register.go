@@ -0,0 +1,181 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// registerPeriodStart returns the end of the last Z-report, or the zero time
+// when the register has never been closed
+func registerPeriodStart(q queryer) (time.Time, error) {
+	var start sql.NullTime
+	err := q.QueryRow("SELECT MAX(period_end) FROM register_closures").Scan(&start)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return start.Time, nil
+}
+
+// buildRegisterReport totals invoices and refunds made after start and up to
+// and including end
+func buildRegisterReport(q queryer, start, end time.Time) (types.RegisterReport, error) {
+	r := types.RegisterReport{Start: start, End: end}
+
+	var first, last sql.NullString
+	var firstAt sql.NullTime
+	var tax float64
+	err := q.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(subtotal), 0), COALESCE(SUM(discount_amount), 0), COALESCE(SUM(tax_amount), 0),
+			MIN(created_at),
+			(SELECT invoice_number FROM invoices WHERE created_at > $1 AND created_at <= $2 ORDER BY id LIMIT 1),
+			(SELECT invoice_number FROM invoices WHERE created_at > $1 AND created_at <= $2 ORDER BY id DESC LIMIT 1)
+		FROM invoices
+		WHERE created_at > $1 AND created_at <= $2`, start, end).
+		Scan(&r.TransactionCount, &r.GrossSales, &r.Discounts, &tax, &firstAt, &first, &last)
+	if err != nil {
+		return r, err
+	}
+	r.FirstInvoice = first.String
+	r.LastInvoice = last.String
+	if start.IsZero() {
+		r.Start = end
+		if firstAt.Valid {
+			r.Start = firstAt.Time
+		}
+	}
+
+	err = q.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(amount + points_payment), 0), COALESCE(SUM(tax_amount), 0)
+		FROM refunds
+		WHERE created_at > $1 AND created_at <= $2`, start, end).
+		Scan(&r.RefundCount, &r.Refunds, &r.RefundTax)
+	if err != nil {
+		return r, err
+	}
+	r.NetSales = r.GrossSales - r.Discounts - (r.Refunds - r.RefundTax)
+	r.TaxCollected = tax - r.RefundTax
+
+	// Refunds are netted against the rate they were charged at
+	rows, err := q.Query(`
+		SELECT rate, SUM(taxable), SUM(tax) FROM (
+			SELECT COALESCE(tax_percentage, 0) AS rate, subtotal - discount_amount AS taxable, tax_amount AS tax
+			FROM invoices
+			WHERE created_at > $1 AND created_at <= $2
+			UNION ALL
+			SELECT tax_percentage, -(amount + points_payment - tax_amount), -tax_amount
+			FROM refunds
+			WHERE created_at > $1 AND created_at <= $2
+		) t
+		GROUP BY rate
+		ORDER BY rate`, start, end)
+	if err != nil {
+		return r, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t types.TaxRateTotal
+		if err := rows.Scan(&t.Rate, &t.Taxable, &t.Tax); err != nil {
+			return r, err
+		}
+		r.TaxByRate = append(r.TaxByRate, t)
+	}
+	if err := rows.Err(); err != nil {
+		return r, err
+	}
+
+	// Points redeemed as payment are reported as their own tender
+	rows, err = q.Query(`
+		SELECT method, SUM(taken), SUM(refunded) FROM (
+			SELECT payment_method AS method, total_amount - points_payment AS taken, 0 AS refunded
+			FROM invoices
+			WHERE created_at > $1 AND created_at <= $2
+			UNION ALL
+			SELECT 'points', points_payment, 0
+			FROM invoices
+			WHERE created_at > $1 AND created_at <= $2 AND points_payment > 0
+			UNION ALL
+			SELECT payment_method, 0, amount
+			FROM refunds
+			WHERE created_at > $1 AND created_at <= $2
+			UNION ALL
+			SELECT 'points', 0, points_payment
+			FROM refunds
+			WHERE created_at > $1 AND created_at <= $2 AND points_payment > 0
+		) t
+		GROUP BY method
+		ORDER BY method`, start, end)
+	if err != nil {
+		return r, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p types.PaymentTotal
+		if err := rows.Scan(&p.Method, &p.Taken, &p.Refunded); err != nil {
+			return r, err
+		}
+		r.Payments = append(r.Payments, p)
+	}
+	return r, rows.Err()
+}
+
+// XReport returns a snapshot of the register since the last Z-report without
+// closing it
+func XReport(db *sql.DB) (types.RegisterReport, error) {
+	start, err := registerPeriodStart(db)
+	if err != nil {
+		return types.RegisterReport{}, err
+	}
+	return buildRegisterReport(db, start, time.Now())
+}
+
+// CloseRegister produces the next numbered Z-report and starts a new period
+func CloseRegister(db *sql.DB, username string) (types.RegisterReport, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return types.RegisterReport{}, err
+	}
+	defer tx.Rollback()
+
+	// Taking the number first locks the counter so closures run one at a time
+	number, err := nextDocumentNumber(tx, "z_report_prefix", "last_z_report_number")
+	if err != nil {
+		return types.RegisterReport{}, err
+	}
+
+	start, err := registerPeriodStart(tx)
+	if err != nil {
+		return types.RegisterReport{}, err
+	}
+
+	// Wait for invoices and refunds being written to commit and hold off new
+	// ones, then end the period at the current time. Anything written after
+	// the closure is stamped later, so consecutive periods leave no gaps.
+	if _, err := tx.Exec("LOCK TABLE invoices, refunds IN SHARE MODE"); err != nil {
+		return types.RegisterReport{}, err
+	}
+	var end time.Time
+	if err := tx.QueryRow("SELECT clock_timestamp()").Scan(&end); err != nil {
+		return types.RegisterReport{}, err
+	}
+
+	r, err := buildRegisterReport(tx, start, end)
+	if err != nil {
+		return r, err
+	}
+	r.Number = number
+
+	_, err = tx.Exec(`
+		INSERT INTO register_closures (
+			z_number, period_start, period_end, gross_sales, discounts, refunds, net_sales,
+			tax_collected, transaction_count, first_invoice, last_invoice, closed_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12)`,
+		number, r.Start, end, r.GrossSales, r.Discounts, r.Refunds, r.NetSales,
+		r.TaxCollected, r.TransactionCount, r.FirstInvoice, r.LastInvoice, username)
+	if err != nil {
+		return r, err
+	}
+
+	return r, tx.Commit()
+}
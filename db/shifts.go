@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// GetOpenShift returns the cashier's open shift, or nil when they have none
+func GetOpenShift(db *sql.DB, cashier string) (*types.Shift, error) {
+	s := types.Shift{Cashier: cashier}
+	err := db.QueryRow(`
+		SELECT id, opening_float, opened_at
+		FROM shifts
+		WHERE cashier = $1 AND closed_at IS NULL`, cashier).
+		Scan(&s.ID, &s.OpeningFloat, &s.OpenedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// OpenShift starts a shift for the cashier with the cash put in the drawer
+func OpenShift(db *sql.DB, cashier string, openingFloat float64) error {
+	open, err := GetOpenShift(db, cashier)
+	if err != nil {
+		return err
+	}
+	if open != nil {
+		return fmt.Errorf("%s already has a shift open since %s", cashier, open.OpenedAt.Format("2006-01-02 15:04"))
+	}
+	_, err = db.Exec("INSERT INTO shifts (cashier, opening_float) VALUES ($1, $2)", cashier, openingFloat)
+	return err
+}
+
+// CloseShift ends the cashier's open shift with the cash counted in the
+// drawer. The expected cash is the opening float plus cash sales less cash
+// refunds made by the cashier during the shift.
+func CloseShift(db *sql.DB, cashier string, countedCash float64) (types.Shift, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return types.Shift{}, err
+	}
+	defer tx.Rollback()
+
+	s := types.Shift{Cashier: cashier, CountedCash: countedCash}
+	err = tx.QueryRow(`
+		SELECT id, opening_float, opened_at
+		FROM shifts
+		WHERE cashier = $1 AND closed_at IS NULL
+		FOR UPDATE`, cashier).
+		Scan(&s.ID, &s.OpeningFloat, &s.OpenedAt)
+	if err == sql.ErrNoRows {
+		return s, fmt.Errorf("%s has no open shift", cashier)
+	}
+	if err != nil {
+		return s, err
+	}
+
+	var taken, refunded float64
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(i.total_amount - i.points_payment), 0)
+		FROM invoices i
+		JOIN sales s ON s.id = i.sale_id
+		WHERE s.cashier = $1 AND i.payment_method = $2 AND i.created_at >= $3`,
+		cashier, types.PaymentCash, s.OpenedAt).Scan(&taken)
+	if err != nil {
+		return s, err
+	}
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0)
+		FROM refunds
+		WHERE username = $1 AND payment_method = $2 AND created_at >= $3`,
+		cashier, types.PaymentCash, s.OpenedAt).Scan(&refunded)
+	if err != nil {
+		return s, err
+	}
+	s.ExpectedCash = s.OpeningFloat + taken - refunded
+
+	var closedAt sql.NullTime
+	err = tx.QueryRow(`
+		UPDATE shifts SET expected_cash = $1, counted_cash = $2, closed_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING closed_at`, s.ExpectedCash, countedCash, s.ID).Scan(&closedAt)
+	if err != nil {
+		return s, err
+	}
+	s.ClosedAt = &closedAt.Time
+
+	return s, tx.Commit()
+}
+
+// RecordVoid logs a cart cleared before payment
+func RecordVoid(db *sql.DB, cashier string, items []types.CartItem) error {
+	var amount float64
+	for _, item := range items {
+		amount += item.Subtotal()
+	}
+	_, err := db.Exec("INSERT INTO voids (cashier, item_count, amount) VALUES ($1, $2, $3)",
+		cashier, len(items), amount)
+	return err
+}
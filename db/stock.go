@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx so stock changes can be
+// recorded inside a caller's transaction
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// RecordStockMovement is the only place product stock is changed. It applies
+// the signed quantity to the product and writes the movement to the ledger.
+func RecordStockMovement(q queryer, movement types.StockMovement) error {
+	var stockAfter, costAfter float64
+	err := q.QueryRow(`
+		UPDATE products
+		SET stock = stock + $1
+		WHERE id = $2
+		RETURNING stock, cost`,
+		movement.Quantity, movement.ProductID).Scan(&stockAfter, &costAfter)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`
+		INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, cost_after, username, reference, note)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))`,
+		movement.ProductID, movement.Type, movement.Quantity, stockAfter, costAfter,
+		movement.Username, movement.Reference, movement.Note)
+	return err
+}
+
+// AdjustStock records a manual adjustment or a physical count. For a count the
+// quantity is the number counted and the difference to the current stock is
+// recorded. Stock written down is also taken out of the product's lots.
+func AdjustStock(db *sql.DB, movement types.StockMovement) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if movement.Type == types.MovementCount {
+		var current float64
+		err = tx.QueryRow("SELECT stock FROM products WHERE id = $1 FOR UPDATE", movement.ProductID).Scan(&current)
+		if err != nil {
+			return err
+		}
+		movement.Quantity -= current
+	}
+
+	if movement.Quantity < 0 {
+		if err := reduceLots(tx, movement.ProductID, -movement.Quantity); err != nil {
+			return err
+		}
+	}
+	if err := RecordStockMovement(tx, movement); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func GetStockMovements(db *sql.DB, productID int) ([]types.StockMovement, error) {
+	rows, err := db.Query(`
+		SELECT id, product_id, movement_type, quantity, stock_after, username,
+			COALESCE(reference, ''), COALESCE(note, ''), created_at
+		FROM stock_movements
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []types.StockMovement
+	for rows.Next() {
+		var m types.StockMovement
+		err := rows.Scan(&m.ID, &m.ProductID, &m.Type, &m.Quantity, &m.StockAfter, &m.Username,
+			&m.Reference, &m.Note, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, m)
+	}
+	return movements, nil
+}
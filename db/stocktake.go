@@ -0,0 +1,248 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// VerifySupervisorPIN checks a PIN entered to approve a supervised action
+func VerifySupervisorPIN(db *sql.DB, pin string) (bool, error) {
+	var stored string
+	err := db.QueryRow("SELECT value FROM settings WHERE key = 'supervisor_pin'").Scan(&stored)
+	if err != nil {
+		return false, err
+	}
+	return stored != "" && pin == stored, nil
+}
+
+// GetCategories returns the distinct product categories in use
+func GetCategories(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT category FROM products WHERE category <> '' ORDER BY category")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []string
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// CreateStocktake starts a count session for the products in the given
+// category and shelf that hold stock, freezing their current stock as the
+// expected quantity and their cost as the value of any variance. An empty
+// category or shelf includes all of them.
+func CreateStocktake(db *sql.DB, name, category, shelf, username string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var stocktakeID int
+	err = tx.QueryRow(`
+		INSERT INTO stocktakes (name, category, shelf, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		name, category, shelf, username).Scan(&stocktakeID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO stocktake_lines (stocktake_id, product_id, expected_quantity, unit_value)
+		SELECT $1, id, stock, cost
+		FROM products
+		WHERE active AND track_stock AND ($2 = '' OR category = $2) AND ($3 = '' OR shelf = $3)`,
+		stocktakeID, category, shelf)
+	if err != nil {
+		return 0, err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return 0, fmt.Errorf("no products match the selected category and shelf")
+	}
+
+	return stocktakeID, tx.Commit()
+}
+
+func GetStocktakes(db *sql.DB) ([]types.Stocktake, error) {
+	rows, err := db.Query(`
+		SELECT id, name, status, category, shelf, created_by, COALESCE(approved_by, ''), created_at, posted_at
+		FROM stocktakes
+		ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stocktakes []types.Stocktake
+	for rows.Next() {
+		var st types.Stocktake
+		var postedAt sql.NullTime
+		err := rows.Scan(&st.ID, &st.Name, &st.Status, &st.Category, &st.Shelf,
+			&st.CreatedBy, &st.ApprovedBy, &st.CreatedAt, &postedAt)
+		if err != nil {
+			return nil, err
+		}
+		if postedAt.Valid {
+			st.PostedAt = &postedAt.Time
+		}
+		stocktakes = append(stocktakes, st)
+	}
+	return stocktakes, nil
+}
+
+// GetStocktake returns a stocktake with its lines ordered by shelf, so the
+// count sheet follows the walk through the store
+func GetStocktake(db *sql.DB, id int) (types.Stocktake, error) {
+	return getStocktake(db, id)
+}
+
+func getStocktake(q queryer, id int) (types.Stocktake, error) {
+	var st types.Stocktake
+	var postedAt sql.NullTime
+	err := q.QueryRow(`
+		SELECT id, name, status, category, shelf, created_by, COALESCE(approved_by, ''), created_at, posted_at
+		FROM stocktakes
+		WHERE id = $1`, id).
+		Scan(&st.ID, &st.Name, &st.Status, &st.Category, &st.Shelf,
+			&st.CreatedBy, &st.ApprovedBy, &st.CreatedAt, &postedAt)
+	if err != nil {
+		return st, err
+	}
+	if postedAt.Valid {
+		st.PostedAt = &postedAt.Time
+	}
+
+	rows, err := q.Query(`
+		SELECT l.id, l.expected_quantity, l.counted_quantity, l.unit_value,
+			p.id, p.name, p.unit, p.quantity_precision, p.category, p.shelf
+		FROM stocktake_lines l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.stocktake_id = $1
+		ORDER BY p.shelf, p.name`, id)
+	if err != nil {
+		return st, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l types.StocktakeLine
+		var counted sql.NullFloat64
+		err := rows.Scan(&l.ID, &l.Expected, &counted, &l.UnitValue,
+			&l.Product.ID, &l.Product.Name, &l.Product.Unit, &l.Product.Precision,
+			&l.Product.Category, &l.Product.Shelf)
+		if err != nil {
+			return st, err
+		}
+		if counted.Valid {
+			l.Counted = &counted.Float64
+		}
+		st.Lines = append(st.Lines, l)
+	}
+	return st, rows.Err()
+}
+
+// SaveStocktakeCounts records counted quantities by line ID. A nil count
+// clears a previous count.
+func SaveStocktakeCounts(db *sql.DB, stocktakeID int, counts map[int]*float64, username string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM stocktakes WHERE id = $1 FOR UPDATE", stocktakeID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != types.StocktakeCounting {
+		return fmt.Errorf("stocktake is already %s", status)
+	}
+
+	for lineID, counted := range counts {
+		_, err = tx.Exec(`
+			UPDATE stocktake_lines
+			SET counted_quantity = $1, counted_by = $2, counted_at = CURRENT_TIMESTAMP
+			WHERE id = $3 AND stocktake_id = $4`,
+			counted, username, lineID, stocktakeID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PostStocktake applies the variance of every counted line to stock once a
+// supervisor has approved it. Uncounted lines are left untouched.
+func PostStocktake(db *sql.DB, stocktakeID int, approvedBy string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the stocktake before reading its lines so no count can be saved
+	// in between and left unposted
+	var status string
+	err = tx.QueryRow("SELECT status FROM stocktakes WHERE id = $1 FOR UPDATE", stocktakeID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != types.StocktakeCounting {
+		return fmt.Errorf("stocktake is no longer open for counting")
+	}
+
+	st, err := getStocktake(tx, stocktakeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE stocktakes
+		SET status = 'posted', approved_by = $1, posted_at = CURRENT_TIMESTAMP
+		WHERE id = $2`,
+		approvedBy, stocktakeID)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range st.Lines {
+		if line.Variance() == 0 {
+			continue
+		}
+		if line.Variance() < 0 {
+			if err := reduceLots(tx, line.Product.ID, -line.Variance()); err != nil {
+				return err
+			}
+		}
+		err = RecordStockMovement(tx, types.StockMovement{
+			ProductID: line.Product.ID,
+			Type:      types.MovementCount,
+			Quantity:  line.Variance(),
+			Username:  approvedBy,
+			Reference: fmt.Sprintf("Stocktake #%d", stocktakeID),
+			Note:      st.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func CancelStocktake(db *sql.DB, stocktakeID int) error {
+	_, err := db.Exec("UPDATE stocktakes SET status = 'cancelled' WHERE id = $1 AND status = 'counting'", stocktakeID)
+	return err
+}
@@ -0,0 +1,64 @@
+// Package export writes tabular reports to CSV, XLSX and JSON files
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Supported export formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatJSON = "json"
+)
+
+var Formats = []string{FormatCSV, FormatXLSX, FormatJSON}
+
+// Column describes one column of a table. Key names the field in JSON output
+// and Title heads the column in CSV and XLSX.
+type Column struct {
+	Key   string
+	Title string
+}
+
+// Table is a named set of rows. Cells hold a string, int, float64 or bool so
+// each format can write numbers and flags in its own types.
+type Table struct {
+	Name    string
+	Columns []Column
+	Rows    [][]any
+}
+
+// Write writes the table to w in the given format
+func Write(w io.Writer, format string, table Table) error {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return WriteCSV(w, table)
+	case FormatXLSX:
+		return WriteXLSX(w, table)
+	case FormatJSON:
+		return WriteJSON(w, table)
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+// formatCell returns the text of a cell, writing numbers in full without
+// exponents
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// WriteJSON writes the table as an array of objects keyed by column, keeping
+// the column order
+func WriteJSON(w io.Writer, table Table) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for r, row := range table.Rows {
+		if r > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for i, c := range table.Columns {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			key, err := marshal(c.Key)
+			if err != nil {
+				return err
+			}
+			var value any
+			if i < len(row) {
+				value = row[i]
+			}
+			encoded, err := marshal(value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteString(": ")
+			buf.Write(encoded)
+		}
+		buf.WriteString("}")
+	}
+	if len(table.Rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// marshal encodes a value without escaping HTML characters, which are common
+// in product names
+func marshal(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
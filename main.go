@@ -56,6 +56,7 @@ func main() {
 			}
 
 			log.Println("Creating main window...")
+			ui.SetCurrentUser(username)
 			mainWindow := ui.NewMainWindow(window, database)
 			if err := mainWindow.Load(); err != nil {
 				log.Printf("Error loading main window: %v", err)
@@ -0,0 +1,235 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Cashiers is activity per cashier over a period
+type Cashiers struct {
+	Range types.TimeRange
+	Lines []types.CashierPerformance
+}
+
+// LoadCashiers totals sales, voids, refunds and shifts per cashier in the
+// period
+func LoadCashiers(database *sql.DB, period types.TimeRange) (Cashiers, error) {
+	lines, err := queryCashiers(database, period)
+	if err != nil {
+		return Cashiers{}, err
+	}
+	return Cashiers{Range: period, Lines: lines}, nil
+}
+
+// queryCashiers gathers each cashier's figures from sales, voids, refunds
+// and shifts opened in the range, ordered by gross sales
+func queryCashiers(database *sql.DB, r types.TimeRange) ([]types.CashierPerformance, error) {
+	byCashier := map[string]*types.CashierPerformance{}
+	cashier := func(name string) *types.CashierPerformance {
+		c, ok := byCashier[name]
+		if !ok {
+			c = &types.CashierPerformance{Cashier: name}
+			byCashier[name] = c
+		}
+		return c
+	}
+
+	// Items count whole units, and a weighed line as a single item
+	rows, err := database.Query(`
+		SELECT s.cashier, COUNT(*), COALESCE(SUM(i.subtotal), 0), COALESCE(SUM(i.discount_amount), 0),
+			COALESCE(SUM((
+				SELECT SUM(CASE WHEN p.quantity_precision = 0 THEN si.quantity ELSE 1 END)
+				FROM sale_items si
+				JOIN products p ON p.id = si.product_id
+				WHERE si.sale_id = s.id
+			)), 0)
+		FROM invoices i
+		JOIN sales s ON s.id = i.sale_id
+		WHERE i.created_at >= $1 AND i.created_at < $2
+		GROUP BY s.cashier`, r.Start, r.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cashier sales: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var transactions int
+		var gross, discounts, items float64
+		if err := rows.Scan(&name, &transactions, &gross, &discounts, &items); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		c := cashier(name)
+		c.Transactions, c.GrossSales, c.Discounts, c.Items = transactions, gross, discounts, items
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = database.Query(`
+		SELECT cashier, COUNT(*), SUM(amount)
+		FROM voids
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY cashier`, r.Start, r.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query voids: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var count int
+		var amount float64
+		if err := rows.Scan(&name, &count, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		c := cashier(name)
+		c.VoidCount, c.VoidAmount = count, amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = database.Query(`
+		SELECT username, COUNT(*), SUM(amount)
+		FROM refunds
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY username`, r.Start, r.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var count int
+		var amount float64
+		if err := rows.Scan(&name, &count, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		c := cashier(name)
+		c.RefundCount, c.RefundAmount = count, amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = database.Query(`
+		SELECT id, cashier, opening_float, opened_at, closed_at,
+			COALESCE(expected_cash, 0), COALESCE(counted_cash, 0)
+		FROM shifts
+		WHERE opened_at >= $1 AND opened_at < $2
+		ORDER BY opened_at`, r.Start, r.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shifts: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s types.Shift
+		var closedAt sql.NullTime
+		err := rows.Scan(&s.ID, &s.Cashier, &s.OpeningFloat, &s.OpenedAt, &closedAt, &s.ExpectedCash, &s.CountedCash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if closedAt.Valid {
+			s.ClosedAt = &closedAt.Time
+		}
+		c := cashier(s.Cashier)
+		c.Shifts = append(c.Shifts, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var lines []types.CashierPerformance
+	for _, c := range byCashier {
+		lines = append(lines, *c)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].GrossSales != lines[j].GrossSales {
+			return lines[i].GrossSales > lines[j].GrossSales
+		}
+		return lines[i].Cashier < lines[j].Cashier
+	})
+	return lines, nil
+}
+
+// Text lists each cashier's figures followed by their shifts
+func (c Cashiers) Text() string {
+	report := fmt.Sprintf("Cashier Report for %s\n\n", c.Range)
+	report += "Cashier         | Trans | Gross Sales    | Avg Basket   | Items/Basket | Discounts    | Voids            | Refunds          | Cash Variance\n"
+	report += "---------------------------------------------------------------------------------------------------------------------------------------------\n"
+	for _, l := range c.Lines {
+		report += fmt.Sprintf("%-15s | %5d | Rp%-12.2f | Rp%-10.2f | %12.1f | Rp%-10.2f | %3d Rp%-10.2f | %3d Rp%-10.2f | %s\n",
+			cashierName(l.Cashier), l.Transactions, l.GrossSales, l.AverageBasket(), l.ItemsPerBasket(), l.Discounts,
+			l.VoidCount, l.VoidAmount, l.RefundCount, l.RefundAmount, formatAmountChange(l.CashVariance()))
+	}
+	if len(c.Lines) == 0 {
+		report += "No cashier activity in this period\n"
+	}
+
+	loc := c.Range.Start.Location()
+	report += "\nShifts\n"
+	report += "Cashier         | Opened           | Closed           | Float          | Expected       | Counted        | Variance\n"
+	report += "---------------------------------------------------------------------------------------------------------------------\n"
+	for _, l := range c.Lines {
+		for _, s := range l.Shifts {
+			if s.ClosedAt == nil {
+				report += fmt.Sprintf("%-15s | %s | %-16s | Rp%-12.2f | %-14s | %-14s | -\n", cashierName(s.Cashier),
+					s.OpenedAt.In(loc).Format("2006-01-02 15:04"), "open", s.OpeningFloat, "-", "-")
+				continue
+			}
+			report += fmt.Sprintf("%-15s | %s | %s | Rp%-12.2f | Rp%-12.2f | Rp%-12.2f | %s\n", cashierName(s.Cashier),
+				s.OpenedAt.In(loc).Format("2006-01-02 15:04"), s.ClosedAt.In(loc).Format("2006-01-02 15:04"),
+				s.OpeningFloat, s.ExpectedCash, s.CountedCash, formatAmountChange(s.Variance()))
+		}
+	}
+	return report
+}
+
+// Table lists each cashier's figures
+func (c Cashiers) Table() export.Table {
+	table := export.Table{
+		Name: "Cashiers",
+		Columns: []export.Column{
+			{Key: "cashier", Title: "Cashier"},
+			{Key: "transactions", Title: "Transactions"},
+			{Key: "gross_sales", Title: "Gross Sales"},
+			{Key: "average_basket", Title: "Average Basket"},
+			{Key: "items_per_basket", Title: "Items per Basket"},
+			{Key: "discounts", Title: "Discounts"},
+			{Key: "voids", Title: "Voids"},
+			{Key: "void_amount", Title: "Void Amount"},
+			{Key: "refunds", Title: "Refunds"},
+			{Key: "refund_amount", Title: "Refund Amount"},
+			{Key: "shifts", Title: "Shifts"},
+			{Key: "cash_variance", Title: "Cash Variance"},
+		},
+	}
+	for _, l := range c.Lines {
+		table.Rows = append(table.Rows, []any{
+			l.Cashier,
+			l.Transactions,
+			roundMoney(l.GrossSales),
+			roundMoney(l.AverageBasket()),
+			roundMoney(l.ItemsPerBasket()),
+			roundMoney(l.Discounts),
+			l.VoidCount,
+			roundMoney(l.VoidAmount),
+			l.RefundCount,
+			roundMoney(l.RefundAmount),
+			len(l.Shifts),
+			roundMoney(l.CashVariance()),
+		})
+	}
+	return table
+}
+
+// cashierName labels sales made before cashiers were recorded
+func cashierName(name string) string {
+	if name == "" {
+		return "(unknown)"
+	}
+	return name
+}
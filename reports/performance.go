@@ -0,0 +1,144 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Performance ranks products by their sales over a period
+type Performance struct {
+	Range types.TimeRange
+	Lines []types.ProductPerformance
+	// DeadStockDays is how long stock can go unsold before it is dead stock
+	DeadStockDays int
+}
+
+// LoadPerformance ranks products by their sales in the period and flags
+// stock that has not sold in the deadStockDays before now
+func LoadPerformance(database *sql.DB, period types.TimeRange, deadStockDays int, now time.Time) (Performance, error) {
+	lines, err := queryPerformance(database, period, now.AddDate(0, 0, -deadStockDays))
+	if err != nil {
+		return Performance{}, err
+	}
+	return Performance{Range: period, Lines: lines, DeadStockDays: deadStockDays}, nil
+}
+
+// Text lists products by revenue rank with their ABC class, other ranks and
+// daily sales velocity, followed by dead stock
+func (p Performance) Text() string {
+	rank := func(r int) string {
+		if r == 0 {
+			return "-"
+		}
+		return strconv.Itoa(r)
+	}
+
+	report := fmt.Sprintf("Product Performance for %s\n", p.Range)
+	report += fmt.Sprintf("ABC classes: A = first %.0f%% of revenue, B = next %.0f%%, C = the rest\n\n",
+		types.ABCShareA, types.ABCShareB-types.ABCShareA)
+	report += "Product Name         | ABC | Rank Rev/Qty/Mgn | Total Sales    | Quantity        | Margin | Per Day    | Days Cover\n"
+	report += "---------------------------------------------------------------------------------------------------------------------\n"
+
+	classRevenue := map[string]float64{}
+	classCount := map[string]int{}
+	var dead []types.ProductPerformance
+	for _, l := range p.Lines {
+		cover := "-"
+		if days, ok := l.DaysOfCover(); ok && l.Product.TrackStock {
+			cover = fmt.Sprintf("%.0f", days)
+		}
+		ranks := fmt.Sprintf("%s/%s/%s", rank(l.RevenueRank), rank(l.QuantityRank), rank(l.MarginRank))
+		report += fmt.Sprintf("%-20s |  %s  | %-16s | Rp%-12.2f | %15s | %5.1f%% | %10.2f | %s\n",
+			l.Product.Name, l.Class, ranks, l.Revenue, l.Product.FormatQuantity(l.Quantity),
+			types.MarginPercent(l.Revenue, l.Cost), l.Velocity, cover)
+
+		classRevenue[l.Class] += l.Revenue
+		classCount[l.Class]++
+		if l.DeadStock {
+			dead = append(dead, l)
+		}
+	}
+
+	report += "---------------------------------------------------------------------------------------------------------------------\n"
+	for _, class := range []string{types.ABCClassA, types.ABCClassB, types.ABCClassC} {
+		report += fmt.Sprintf("Class %s: %d products, Rp%.2f\n", class, classCount[class], classRevenue[class])
+	}
+
+	report += fmt.Sprintf("\nDead Stock (no sales in %d days)\n", p.DeadStockDays)
+	report += "Product Name         | On Hand         | Stock Value    | Last Sold\n"
+	report += "--------------------------------------------------------------------\n"
+	var deadValue float64
+	for _, l := range dead {
+		lastSold := "never"
+		if l.LastSold != nil {
+			lastSold = l.LastSold.In(p.Range.Start.Location()).Format("2006-01-02")
+		}
+		value := l.Product.Stock * l.Product.Cost
+		deadValue += value
+		report += fmt.Sprintf("%-20s | %15s | Rp%-12.2f | %s\n", l.Product.Name,
+			l.Product.FormatQuantity(l.Product.Stock), value, lastSold)
+	}
+	if len(dead) == 0 {
+		report += "None\n"
+	} else {
+		report += fmt.Sprintf("Total dead stock value: Rp%.2f\n", deadValue)
+	}
+	return report
+}
+
+// Table lists each product's classes, ranks, sales and velocity. Unranked
+// products and unknown dates are left empty.
+func (p Performance) Table() export.Table {
+	table := export.Table{
+		Name: "Product Performance",
+		Columns: []export.Column{
+			{Key: "sku", Title: "SKU"},
+			{Key: "product", Title: "Product"},
+			{Key: "class", Title: "ABC Class"},
+			{Key: "revenue_rank", Title: "Revenue Rank"},
+			{Key: "quantity_rank", Title: "Quantity Rank"},
+			{Key: "margin_rank", Title: "Margin Rank"},
+			{Key: "quantity", Title: "Quantity"},
+			{Key: "revenue", Title: "Total Sales"},
+			{Key: "margin_percent", Title: "Margin %"},
+			{Key: "per_day", Title: "Per Day"},
+			{Key: "stock", Title: "On Hand"},
+			{Key: "last_sold", Title: "Last Sold"},
+			{Key: "dead_stock", Title: "Dead Stock"},
+		},
+	}
+	rank := func(r int) any {
+		if r == 0 {
+			return nil
+		}
+		return r
+	}
+	for _, l := range p.Lines {
+		var lastSold any
+		if l.LastSold != nil {
+			lastSold = l.LastSold.In(p.Range.Start.Location()).Format("2006-01-02")
+		}
+		table.Rows = append(table.Rows, []any{
+			l.Product.SKU,
+			l.Product.Name,
+			l.Class,
+			rank(l.RevenueRank),
+			rank(l.QuantityRank),
+			rank(l.MarginRank),
+			l.Product.RoundQuantity(l.Quantity),
+			roundMoney(l.Revenue),
+			math.Round(types.MarginPercent(l.Revenue, l.Cost)*10) / 10,
+			math.Round(l.Velocity*1000) / 1000,
+			l.Product.RoundQuantity(l.Product.Stock),
+			lastSold,
+			l.DeadStock,
+		})
+	}
+	return table
+}
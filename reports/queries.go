@@ -0,0 +1,202 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/db"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// querySales returns sales per product and per modifier in the range, best
+// sellers first
+func querySales(database *sql.DB, r types.TimeRange) (types.SalesReport, error) {
+	report := types.SalesReport{Range: r}
+
+	rows, err := database.Query(`
+		SELECT
+			p.id,
+			COALESCE(p.sku, ''),
+			p.name,
+			p.unit,
+			p.quantity_precision,
+			SUM(si.quantity) AS total_quantity,
+			SUM(si.quantity * si.price_at_sale) AS total_sales,
+			SUM(si.quantity * si.cost_at_sale) AS total_cost
+		FROM sales s
+		JOIN sale_items si ON s.id = si.sale_id
+		JOIN products p ON si.product_id = p.id
+		WHERE s.created_at >= $1 AND s.created_at < $2
+		GROUP BY p.id, p.sku, p.name, p.unit, p.quantity_precision
+		ORDER BY total_sales DESC`, r.Start, r.End)
+	if err != nil {
+		return report, fmt.Errorf("failed to query sales data: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l types.SalesReportLine
+		err := rows.Scan(&l.Product.ID, &l.Product.SKU, &l.Product.Name, &l.Product.Unit, &l.Product.Precision,
+			&l.Quantity, &l.Revenue, &l.Cost)
+		if err != nil {
+			return report, fmt.Errorf("failed to scan row: %v", err)
+		}
+		report.Lines = append(report.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	rows, err = database.Query(`
+		SELECT m.name, SUM(si.quantity), SUM(si.quantity * m.price_delta) AS revenue
+		FROM sales s
+		JOIN sale_items si ON s.id = si.sale_id
+		JOIN sale_item_modifiers m ON m.sale_item_id = si.id
+		WHERE s.created_at >= $1 AND s.created_at < $2
+		GROUP BY m.name
+		ORDER BY revenue DESC, m.name`, r.Start, r.End)
+	if err != nil {
+		return report, fmt.Errorf("failed to query modifier data: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m types.ModifierSalesLine
+		if err := rows.Scan(&m.Name, &m.Quantity, &m.Revenue); err != nil {
+			return report, fmt.Errorf("failed to scan row: %v", err)
+		}
+		report.Modifiers = append(report.Modifiers, m)
+	}
+	return report, rows.Err()
+}
+
+// queryTrends returns revenue in the range by day, by weekday and hour, and
+// by payment method. Days and hours are in the range's time zone. Days
+// without sales are included with zero revenue.
+func queryTrends(database *sql.DB, r types.TimeRange) (types.SalesTrends, error) {
+	var trends types.SalesTrends
+
+	rows, err := database.Query(`
+		SELECT TO_CHAR(s.created_at AT TIME ZONE $3, 'YYYY-MM-DD'),
+			EXTRACT(DOW FROM s.created_at AT TIME ZONE $3)::int,
+			EXTRACT(HOUR FROM s.created_at AT TIME ZONE $3)::int,
+			SUM(si.quantity * si.price_at_sale)
+		FROM sales s
+		JOIN sale_items si ON s.id = si.sale_id
+		WHERE s.created_at >= $1 AND s.created_at < $2
+		GROUP BY 1, 2, 3
+		ORDER BY 1`, r.Start, r.End, r.Start.Location().String())
+	if err != nil {
+		return trends, fmt.Errorf("failed to query sales trends: %v", err)
+	}
+	defer rows.Close()
+
+	daily := map[string]float64{}
+	for rows.Next() {
+		var day string
+		var weekday, hour int
+		var revenue float64
+		if err := rows.Scan(&day, &weekday, &hour, &revenue); err != nil {
+			return trends, fmt.Errorf("failed to scan row: %v", err)
+		}
+		daily[day] += revenue
+		trends.ByHour[weekday][hour] += revenue
+	}
+	if err := rows.Err(); err != nil {
+		return trends, err
+	}
+
+	for _, day := range r.Days() {
+		trends.Daily = append(trends.Daily, types.DailyTotal{Day: day, Revenue: daily[day.Format("2006-01-02")]})
+	}
+
+	rows, err = database.Query(`
+		SELECT payment_method, SUM(total_amount - points_payment)
+		FROM invoices
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY payment_method
+		ORDER BY 2 DESC`, r.Start, r.End)
+	if err != nil {
+		return trends, fmt.Errorf("failed to query payments: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p types.PaymentTotal
+		if err := rows.Scan(&p.Method, &p.Taken); err != nil {
+			return trends, fmt.Errorf("failed to scan row: %v", err)
+		}
+		trends.Payments = append(trends.Payments, p)
+	}
+	return trends, rows.Err()
+}
+
+// queryPerformance ranks every active product, and any archived product sold
+// in the range, by its sales in the range. Stocked products with stock on
+// hand and no sale since deadStockSince are flagged as dead stock.
+func queryPerformance(database *sql.DB, r types.TimeRange, deadStockSince time.Time) ([]types.ProductPerformance, error) {
+	products, err := db.GetProducts(database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load products: %v", err)
+	}
+	sales, err := querySales(database, r)
+	if err != nil {
+		return nil, err
+	}
+	sold := map[int]types.SalesReportLine{}
+	for _, l := range sales.Lines {
+		sold[l.Product.ID] = l
+	}
+	active := map[int]bool{}
+	for _, p := range products {
+		active[p.ID] = true
+	}
+	for _, l := range sales.Lines {
+		if !active[l.Product.ID] {
+			products = append(products, l.Product)
+		}
+	}
+
+	rows, err := database.Query(`
+		SELECT si.product_id, MAX(s.created_at)
+		FROM sales s
+		JOIN sale_items si ON s.id = si.sale_id
+		GROUP BY si.product_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last sales: %v", err)
+	}
+	defer rows.Close()
+
+	lastSold := map[int]time.Time{}
+	for rows.Next() {
+		var productID int
+		var at time.Time
+		if err := rows.Scan(&productID, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		lastSold[productID] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	days := float64(len(r.Days()))
+	var lines []types.ProductPerformance
+	for _, p := range products {
+		line := types.ProductPerformance{SalesReportLine: sold[p.ID]}
+		line.Product = p
+		if days > 0 {
+			line.Velocity = line.Quantity / days
+		}
+		at, ok := lastSold[p.ID]
+		if ok {
+			line.LastSold = &at
+		}
+		line.DeadStock = p.TrackStock && p.Stock > 0 && (!ok || at.Before(deadStockSince))
+		lines = append(lines, line)
+	}
+
+	types.RankPerformance(lines)
+	return lines, nil
+}
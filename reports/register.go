@@ -0,0 +1,113 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/db"
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Register is an X or Z register report
+type Register struct {
+	types.RegisterReport
+}
+
+// NewRegister wraps a register report with its times shown in loc
+func NewRegister(report types.RegisterReport, loc *time.Location) Register {
+	report.Start = report.Start.In(loc)
+	report.End = report.End.In(loc)
+	return Register{RegisterReport: report}
+}
+
+// LoadXReport snapshots the open register period without closing it
+func LoadXReport(database *sql.DB, loc *time.Location) (Register, error) {
+	report, err := db.XReport(database)
+	if err != nil {
+		return Register{}, err
+	}
+	return NewRegister(report, loc), nil
+}
+
+// Text lays out the report for the receipt printer
+func (r Register) Text() string {
+	report := r.RegisterReport
+	line := func(label string, amount float64) string {
+		return fmt.Sprintf("%-17s%16s\n", label, fmt.Sprintf("Rp%.2f", amount))
+	}
+
+	text := "=================================\n"
+	if report.Number != "" {
+		text += fmt.Sprintf("Z REPORT %s\n", report.Number)
+	} else {
+		text += "X REPORT (NOT CLOSED)\n"
+	}
+	text += "=================================\n"
+	text += fmt.Sprintf("From: %s\n", report.Start.Format("2006-01-02 15:04:05"))
+	text += fmt.Sprintf("To:   %s\n", report.End.Format("2006-01-02 15:04:05"))
+	text += fmt.Sprintf("Transactions: %d\n", report.TransactionCount)
+	if report.TransactionCount > 0 {
+		text += fmt.Sprintf("First Invoice: %s\n", report.FirstInvoice)
+		text += fmt.Sprintf("Last Invoice:  %s\n", report.LastInvoice)
+	}
+	text += "---------------------------------\n"
+	text += line("Gross Sales", report.GrossSales)
+	text += line("Discounts", -report.Discounts)
+	text += line(fmt.Sprintf("Refunds (%d)", report.RefundCount), -(report.Refunds - report.RefundTax))
+	text += line("Net Sales", report.NetSales)
+	text += line("Tax Collected", report.TaxCollected)
+	text += "---------------------------------\n"
+	text += "Tax by Rate\n"
+	for _, t := range report.TaxByRate {
+		text += fmt.Sprintf("%5.1f%% on Rp%-11.2f Rp%.2f\n", t.Rate, t.Taxable, t.Tax)
+	}
+	text += "---------------------------------\n"
+	text += "Payments\n"
+	for _, p := range report.Payments {
+		text += line(strings.ToUpper(p.Method), p.Taken)
+		if p.Refunded > 0 {
+			text += line("  Refunded", -p.Refunded)
+		}
+	}
+	text += "=================================\n"
+	return text
+}
+
+// Table lists the report's figures as label and amount pairs
+func (r Register) Table() export.Table {
+	table := export.Table{
+		Name: "Register",
+		Columns: []export.Column{
+			{Key: "item", Title: "Item"},
+			{Key: "amount", Title: "Amount"},
+		},
+	}
+	add := func(item string, amount any) {
+		table.Rows = append(table.Rows, []any{item, amount})
+	}
+	add("report_number", r.Number)
+	add("period_start", r.Start.Format(time.RFC3339))
+	add("period_end", r.End.Format(time.RFC3339))
+	add("transactions", r.TransactionCount)
+	add("first_invoice", r.FirstInvoice)
+	add("last_invoice", r.LastInvoice)
+	add("gross_sales", roundMoney(r.GrossSales))
+	add("discounts", roundMoney(r.Discounts))
+	add("refund_count", r.RefundCount)
+	add("refunds", roundMoney(r.Refunds))
+	add("refund_tax", roundMoney(r.RefundTax))
+	add("net_sales", roundMoney(r.NetSales))
+	add("tax_collected", roundMoney(r.TaxCollected))
+	for _, t := range r.TaxByRate {
+		add(fmt.Sprintf("taxable_at_%g", t.Rate), roundMoney(t.Taxable))
+		add(fmt.Sprintf("tax_at_%g", t.Rate), roundMoney(t.Tax))
+	}
+	for _, p := range r.Payments {
+		add("taken_"+strings.ToLower(p.Method), roundMoney(p.Taken))
+		add("refunded_"+strings.ToLower(p.Method), roundMoney(p.Refunded))
+	}
+	return table
+}
@@ -0,0 +1,60 @@
+// Package reports loads report data from the database and renders it as text
+// or as CSV, XLSX and JSON tables. The reports screen and the report command
+// share it, so both produce the same figures.
+package reports
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// FormatText renders a report as the plain text shown on screen
+const FormatText = "text"
+
+// Formats lists every format a report can be rendered in
+var Formats = append([]string{FormatText}, export.Formats...)
+
+// Report is report data that can be rendered as text or as a table
+type Report interface {
+	Text() string
+	Table() export.Table
+}
+
+// Render writes the report to w as text or as a table in an export format
+func Render(w io.Writer, format string, report Report) error {
+	if strings.ToLower(format) == FormatText {
+		_, err := io.WriteString(w, report.Text())
+		return err
+	}
+	return export.Write(w, format, report.Table())
+}
+
+// formatAmountChange shows a change in money with its sign
+func formatAmountChange(change float64) string {
+	if change < 0 {
+		return fmt.Sprintf("-Rp%.2f", -change)
+	}
+	return fmt.Sprintf("+Rp%.2f", change)
+}
+
+// formatPercentChange shows the change from previous to current as a signed
+// percentage, or "new" when there was nothing before
+func formatPercentChange(current, previous float64) string {
+	change, ok := types.PercentChange(current, previous)
+	if !ok {
+		if current == 0 {
+			return "-"
+		}
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", change)
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,171 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Sales is product sales for a period, optionally compared with another
+type Sales struct {
+	types.SalesReport
+	// Previous is the compared period's sales, or nil for no comparison
+	Previous *types.SalesReport
+	// Packages shows quantities in each product's largest packaging unit when
+	// set
+	Packages map[int][]types.ProductPackage
+}
+
+// LoadSales loads sales for the period and, when compare is set, for the
+// period to compare against
+func LoadSales(database *sql.DB, period types.TimeRange, compare *types.TimeRange) (Sales, error) {
+	var s Sales
+	var err error
+	s.SalesReport, err = querySales(database, period)
+	if err != nil {
+		return s, err
+	}
+	if compare != nil {
+		previous, err := querySales(database, *compare)
+		if err != nil {
+			return s, err
+		}
+		s.Previous = &previous
+	}
+	return s, nil
+}
+
+// Text lists sales per product and per modifier. When a compared period is
+// set, a comparison against it follows.
+func (s Sales) Text() string {
+	var report string
+	report += fmt.Sprintf("Sales Report for %s (%s)\n\n", s.Range, s.Range.Start.Location())
+	report += "Product Name         |   Quantity      | Total Sales    | Gross Profit   | Margin\n"
+	report += "-------------------------------------------------------------------------------------\n"
+
+	for _, line := range s.Lines {
+		quantityText := line.Product.FormatQuantity(line.Quantity)
+		if units := s.Packages[line.Product.ID]; len(units) > 0 {
+			quantityText = line.Product.FormatInPackage(line.Quantity, units[len(units)-1])
+		}
+		report += fmt.Sprintf("%-20s | %15s | Rp%-12.2f | Rp%-12.2f | %5.1f%%\n", line.Product.Name,
+			quantityText, line.Revenue, line.GrossProfit(), types.MarginPercent(line.Revenue, line.Cost))
+	}
+
+	totalRevenue, totalCOGS := s.Totals()
+	report += "-------------------------------------------------------------------------------------\n"
+	report += fmt.Sprintf("Total Revenue: Rp%.2f\n", totalRevenue)
+	report += fmt.Sprintf("Cost of Goods Sold: Rp%.2f\n", totalCOGS)
+	report += fmt.Sprintf("Gross Profit: Rp%.2f (%.1f%%)\n", totalRevenue-totalCOGS, types.MarginPercent(totalRevenue, totalCOGS))
+
+	if len(s.Modifiers) > 0 {
+		report += "\nModifiers            |   Times Chosen  | Added Revenue\n"
+		report += "------------------------------------------------------\n"
+		for _, m := range s.Modifiers {
+			report += fmt.Sprintf("%-20s | %15g | Rp%.2f\n", m.Name, m.Quantity, m.Revenue)
+		}
+	}
+
+	if s.Previous != nil {
+		report += formatSalesComparison(s.SalesReport, *s.Previous)
+	}
+
+	return report
+}
+
+// formatSalesComparison shows the change in revenue per product and in the
+// totals between two periods
+func formatSalesComparison(current, previous types.SalesReport) string {
+	report := fmt.Sprintf("\nCompared with %s\n", previous.Range)
+	report += "Product Name         | This Period    | Compared With  | Change         | Change %\n"
+	report += "-------------------------------------------------------------------------------------\n"
+	for _, c := range types.CompareSales(current, previous) {
+		report += fmt.Sprintf("%-20s | Rp%-12.2f | Rp%-12.2f | %-14s | %s\n", c.Product.Name,
+			c.Current.Revenue, c.Previous.Revenue,
+			formatAmountChange(c.Current.Revenue-c.Previous.Revenue),
+			formatPercentChange(c.Current.Revenue, c.Previous.Revenue))
+	}
+
+	revenue, cost := current.Totals()
+	prevRevenue, prevCost := previous.Totals()
+	report += "-------------------------------------------------------------------------------------\n"
+	for _, total := range []struct {
+		label             string
+		current, previous float64
+	}{
+		{"Total Revenue", revenue, prevRevenue},
+		{"Cost of Goods Sold", cost, prevCost},
+		{"Gross Profit", revenue - cost, prevRevenue - prevCost},
+	} {
+		report += fmt.Sprintf("%-20s | Rp%-12.2f | Rp%-12.2f | %-14s | %s\n", total.label,
+			total.current, total.previous,
+			formatAmountChange(total.current-total.previous),
+			formatPercentChange(total.current, total.previous))
+	}
+	return report
+}
+
+// Table lays out product sales with money rounded to the cent and quantities
+// in each product's base unit. When a compared period is set, each row also
+// carries the compared period's figures and the change.
+func (s Sales) Table() export.Table {
+	table := export.Table{
+		Name: "Sales",
+		Columns: []export.Column{
+			{Key: "sku", Title: "SKU"},
+			{Key: "product", Title: "Product"},
+			{Key: "unit", Title: "Unit"},
+			{Key: "quantity", Title: "Quantity"},
+			{Key: "revenue", Title: "Total Sales"},
+			{Key: "cost", Title: "Cost of Goods"},
+			{Key: "gross_profit", Title: "Gross Profit"},
+			{Key: "margin_percent", Title: "Margin %"},
+		},
+	}
+	row := func(line types.SalesReportLine) []any {
+		return []any{
+			line.Product.SKU,
+			line.Product.Name,
+			line.Product.Unit,
+			line.Product.RoundQuantity(line.Quantity),
+			roundMoney(line.Revenue),
+			roundMoney(line.Cost),
+			roundMoney(line.GrossProfit()),
+			math.Round(types.MarginPercent(line.Revenue, line.Cost)*10) / 10,
+		}
+	}
+
+	if s.Previous == nil {
+		for _, line := range s.Lines {
+			table.Rows = append(table.Rows, row(line))
+		}
+		return table
+	}
+
+	table.Columns = append(table.Columns,
+		export.Column{Key: "compared_quantity", Title: "Compared Quantity"},
+		export.Column{Key: "compared_revenue", Title: "Compared Sales"},
+		export.Column{Key: "revenue_change", Title: "Sales Change"},
+		export.Column{Key: "revenue_change_percent", Title: "Sales Change %"},
+	)
+	for _, c := range types.CompareSales(s.SalesReport, *s.Previous) {
+		// Products only sold in the compared period still need their details
+		current := c.Current
+		current.Product = c.Product
+
+		var percent any
+		if change, ok := types.PercentChange(c.Current.Revenue, c.Previous.Revenue); ok {
+			percent = math.Round(change*10) / 10
+		}
+		table.Rows = append(table.Rows, append(row(current),
+			c.Product.RoundQuantity(c.Previous.Quantity),
+			roundMoney(c.Previous.Revenue),
+			roundMoney(c.Current.Revenue-c.Previous.Revenue),
+			percent,
+		))
+	}
+	return table
+}
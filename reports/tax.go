@@ -0,0 +1,132 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Tax is the tax charged on invoices and returned on refunds over a period,
+// for VAT (PPN) filing
+type Tax struct {
+	Range     types.TimeRange
+	Documents []types.TaxDocument
+}
+
+// LoadTax loads every invoice and refund in the period
+func LoadTax(database *sql.DB, period types.TimeRange) (Tax, error) {
+	documents, err := queryTax(database, period)
+	if err != nil {
+		return Tax{}, err
+	}
+	return Tax{Range: period, Documents: documents}, nil
+}
+
+// queryTax returns invoices and refunds in the range in date order. The
+// taxable base is the amount after discounts and before tax, and refunds are
+// negative at the rate their invoice was charged.
+func queryTax(database *sql.DB, r types.TimeRange) ([]types.TaxDocument, error) {
+	rows, err := database.Query(`
+		SELECT i.created_at, i.invoice_number, $3::text, '', COALESCE(i.customer_name, ''),
+			COALESCE(i.tax_percentage, 0), COALESCE(i.subtotal, 0) - i.discount_amount, COALESCE(i.tax_amount, 0)
+		FROM invoices i
+		WHERE i.created_at >= $1 AND i.created_at < $2
+		UNION ALL
+		SELECT rf.created_at, rf.refund_number, $4::text, i.invoice_number, COALESCE(i.customer_name, ''),
+			rf.tax_percentage, -(rf.amount + rf.points_payment - rf.tax_amount), -rf.tax_amount
+		FROM refunds rf
+		JOIN invoices i ON i.id = rf.invoice_id
+		WHERE rf.created_at >= $1 AND rf.created_at < $2
+		ORDER BY 1, 2`, r.Start, r.End, types.TaxDocumentInvoice, types.TaxDocumentRefund)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax: %v", err)
+	}
+	defer rows.Close()
+
+	var documents []types.TaxDocument
+	for rows.Next() {
+		var d types.TaxDocument
+		err := rows.Scan(&d.Date, &d.Number, &d.Type, &d.Invoice, &d.Customer, &d.Rate, &d.Taxable, &d.Tax)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		d.Date = d.Date.In(r.Start.Location())
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
+
+// Text summarizes tax per rate with refunds netted. Sales charged no tax are
+// shown as exempt rather than as a taxable base.
+func (t Tax) Text() string {
+	report := fmt.Sprintf("Tax Report for %s (%s)\n\n", t.Range, t.Range.Start.Location())
+	report += "Rate     | Invoices | Refunds | Sales Base     | Refunded Base  | Net Base       | Net Tax\n"
+	report += "------------------------------------------------------------------------------------------------\n"
+
+	var taxable, exempt, collected, refunded float64
+	for _, s := range types.SummarizeTax(t.Documents) {
+		rate := fmt.Sprintf("%.2f%%", s.Rate)
+		if s.Exempt() {
+			rate = "Exempt"
+			exempt += s.Taxable
+		} else {
+			taxable += s.Taxable
+		}
+		collected += s.Tax + s.RefundedTax
+		refunded += s.RefundedTax
+		report += fmt.Sprintf("%-8s | %8d | %7d | Rp%-12.2f | Rp%-12.2f | Rp%-12.2f | Rp%.2f\n",
+			rate, s.Invoices, s.Refunds, s.Taxable+s.RefundedTaxable, s.RefundedTaxable, s.Taxable, s.Tax)
+	}
+
+	report += "------------------------------------------------------------------------------------------------\n"
+	report += fmt.Sprintf("Taxable Base (DPP): Rp%.2f\n", taxable)
+	report += fmt.Sprintf("Exempt Sales:       Rp%.2f\n", exempt)
+	report += fmt.Sprintf("Tax Collected:      Rp%.2f\n", collected)
+	report += fmt.Sprintf("Tax Refunded:       Rp%.2f\n", refunded)
+	report += fmt.Sprintf("Net Tax (PPN):      Rp%.2f\n", collected-refunded)
+	return report
+}
+
+// Table lists each invoice and refund with its tax period, so the rows can be
+// grouped by month and rate for filing. Exempt amounts have their own column
+// and are left out of the taxable base.
+func (t Tax) Table() export.Table {
+	table := export.Table{
+		Name: "Tax",
+		Columns: []export.Column{
+			{Key: "tax_period", Title: "Tax Period"},
+			{Key: "date", Title: "Date"},
+			{Key: "document", Title: "Document"},
+			{Key: "type", Title: "Type"},
+			{Key: "invoice", Title: "Invoice"},
+			{Key: "customer", Title: "Customer"},
+			{Key: "rate", Title: "Rate %"},
+			{Key: "taxable_base", Title: "Taxable Base (DPP)"},
+			{Key: "tax", Title: "Tax (PPN)"},
+			{Key: "exempt", Title: "Exempt"},
+			{Key: "total", Title: "Total"},
+		},
+	}
+	for _, d := range t.Documents {
+		taxable, exempt := d.Taxable, 0.0
+		if d.Exempt() {
+			taxable, exempt = 0, d.Taxable
+		}
+		table.Rows = append(table.Rows, []any{
+			d.Date.Format("2006-01"),
+			d.Date.Format("2006-01-02 15:04"),
+			d.Number,
+			d.Type,
+			d.Invoice,
+			d.Customer,
+			d.Rate,
+			roundMoney(taxable),
+			roundMoney(d.Tax),
+			roundMoney(exempt),
+			roundMoney(d.Taxable + d.Tax),
+		})
+	}
+	return table
+}
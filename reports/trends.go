@@ -0,0 +1,59 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Trends is revenue over a period by day, hour and payment method
+type Trends struct {
+	types.SalesTrends
+	Range types.TimeRange
+}
+
+// LoadTrends loads revenue trends for the period
+func LoadTrends(database *sql.DB, period types.TimeRange) (Trends, error) {
+	trends, err := queryTrends(database, period)
+	if err != nil {
+		return Trends{}, err
+	}
+	return Trends{SalesTrends: trends, Range: period}, nil
+}
+
+// Text lists revenue per day followed by the amount taken per payment method
+func (t Trends) Text() string {
+	report := fmt.Sprintf("Daily Revenue for %s\n\n", t.Range)
+	var total float64
+	for _, d := range t.Daily {
+		report += fmt.Sprintf("%s %-3s | Rp%.2f\n", d.Day.Format("2006-01-02"), d.Day.Format("Mon"), d.Revenue)
+		total += d.Revenue
+	}
+	report += "----------------------------------\n"
+	report += fmt.Sprintf("Total          | Rp%.2f\n", total)
+
+	report += "\nPayment Method | Taken\n"
+	report += "----------------------------------\n"
+	for _, p := range t.Payments {
+		report += fmt.Sprintf("%-14s | Rp%.2f\n", strings.ToUpper(p.Method), p.Taken)
+	}
+	return report
+}
+
+// Table lists revenue per day
+func (t Trends) Table() export.Table {
+	table := export.Table{
+		Name: "Daily Revenue",
+		Columns: []export.Column{
+			{Key: "date", Title: "Date"},
+			{Key: "revenue", Title: "Revenue"},
+		},
+	}
+	for _, d := range t.Daily {
+		table.Rows = append(table.Rows, []any{d.Day.Format("2006-01-02"), roundMoney(d.Revenue)})
+	}
+	return table
+}
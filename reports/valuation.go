@@ -0,0 +1,157 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hendrisulistya/cashier-app/export"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+// Valuation is stock on hand at the end of a day, valued at cost and at
+// retail. Each product's Stock, Cost and Price are as of that moment.
+type Valuation struct {
+	Day      time.Time
+	Products []types.Product
+}
+
+// LoadValuation rebuilds stock on hand at the end of day in the store time
+// zone from the stock ledger
+func LoadValuation(database *sql.DB, day time.Time) (Valuation, error) {
+	asOf := types.NewTimeRange(day, day, day.Location()).End
+	products, err := queryValuation(database, asOf)
+	if err != nil {
+		return Valuation{}, err
+	}
+	return Valuation{Day: day, Products: products}, nil
+}
+
+// queryValuation returns every stocked product with stock on hand before
+// asOf. Stock and cost come from the last stock movement before then, and
+// price from the price timeline, falling back to the current price.
+func queryValuation(database *sql.DB, asOf time.Time) ([]types.Product, error) {
+	rows, err := database.Query(`
+		SELECT p.id, COALESCE(p.sku, ''), p.name, p.category, p.unit, p.quantity_precision,
+			m.stock_after, COALESCE(m.cost_after, p.cost),
+			COALESCE((
+				SELECT pp.price
+				FROM product_prices pp
+				WHERE pp.product_id = p.id AND pp.effective_from < $1
+				ORDER BY pp.effective_from DESC, pp.id DESC
+				LIMIT 1
+			), p.price)
+		FROM products p
+		JOIN LATERAL (
+			SELECT sm.stock_after, sm.cost_after
+			FROM stock_movements sm
+			WHERE sm.product_id = p.id AND sm.created_at < $1
+			ORDER BY sm.created_at DESC, sm.id DESC
+			LIMIT 1
+		) m ON true
+		WHERE p.track_stock AND m.stock_after <> 0
+		ORDER BY p.category, p.name`, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock history: %v", err)
+	}
+	defer rows.Close()
+
+	var products []types.Product
+	for rows.Next() {
+		var p types.Product
+		err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Category, &p.Unit, &p.Precision, &p.Stock, &p.Cost, &p.Price)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		p.TrackStock = true
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// Totals returns the value of all stock at cost and at retail
+func (v Valuation) Totals() (cost, retail float64) {
+	for _, p := range v.Products {
+		cost += p.Stock * p.Cost
+		retail += p.Stock * p.Price
+	}
+	return cost, retail
+}
+
+// Text lists each product's stock and value grouped by category, with a
+// subtotal per category
+func (v Valuation) Text() string {
+	report := fmt.Sprintf("Inventory Valuation as of end of %s (%s)\n\n", v.Day.Format("2006-01-02"), v.Day.Location())
+	report += "Product Name         |    On Hand      | Unit Cost    | Cost Value     | Retail Value\n"
+	report += "--------------------------------------------------------------------------------------\n"
+
+	var categoryCost, categoryRetail float64
+	subtotal := func(category string) {
+		report += fmt.Sprintf("%-20s   %15s   %12s   Rp%-12.2f   Rp%.2f\n\n",
+			"Subtotal", "", "", categoryCost, categoryRetail)
+		categoryCost, categoryRetail = 0, 0
+	}
+	for i, p := range v.Products {
+		if i == 0 || p.Category != v.Products[i-1].Category {
+			if i > 0 {
+				subtotal(v.Products[i-1].Category)
+			}
+			report += fmt.Sprintf("[%s]\n", categoryName(p.Category))
+		}
+		cost, retail := p.Stock*p.Cost, p.Stock*p.Price
+		report += fmt.Sprintf("%-20s | %15s | Rp%-10.2f | Rp%-12.2f | Rp%.2f\n",
+			p.Name, p.FormatQuantity(p.Stock), p.Cost, cost, retail)
+		categoryCost += cost
+		categoryRetail += retail
+	}
+	if len(v.Products) > 0 {
+		subtotal(v.Products[len(v.Products)-1].Category)
+	}
+
+	cost, retail := v.Totals()
+	report += "--------------------------------------------------------------------------------------\n"
+	report += fmt.Sprintf("Total at Cost:   Rp%.2f\n", cost)
+	report += fmt.Sprintf("Total at Retail: Rp%.2f\n", retail)
+	report += fmt.Sprintf("Potential Margin: %.1f%%\n", types.MarginPercent(retail, cost))
+	return report
+}
+
+// Table lists each product's stock and value
+func (v Valuation) Table() export.Table {
+	table := export.Table{
+		Name: "Inventory Valuation",
+		Columns: []export.Column{
+			{Key: "sku", Title: "SKU"},
+			{Key: "product", Title: "Product"},
+			{Key: "category", Title: "Category"},
+			{Key: "unit", Title: "Unit"},
+			{Key: "on_hand", Title: "On Hand"},
+			{Key: "unit_cost", Title: "Unit Cost"},
+			{Key: "unit_price", Title: "Unit Price"},
+			{Key: "cost_value", Title: "Cost Value"},
+			{Key: "retail_value", Title: "Retail Value"},
+		},
+	}
+	for _, p := range v.Products {
+		table.Rows = append(table.Rows, []any{
+			p.SKU,
+			p.Name,
+			p.Category,
+			p.Unit,
+			p.RoundQuantity(p.Stock),
+			p.Cost,
+			p.Price,
+			roundMoney(p.Stock * p.Cost),
+			roundMoney(p.Stock * p.Price),
+		})
+	}
+	return table
+}
+
+// categoryName labels products without a category
+func categoryName(category string) string {
+	if category == "" {
+		return "Uncategorized"
+	}
+	return category
+}
@@ -1,15 +1,833 @@
 package types
 
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
 // Product represents a store item
 type Product struct {
 	ID    int
+	SKU   string
 	Name  string
 	Price float64
-	Stock int
+	// Cost is the weighted-average cost of the stock on hand
+	Cost  float64
+	Stock float64
+	// Category and Shelf group products for browsing and stocktakes
+	Category string
+	Shelf    string
+	// ParentID links a variant such as "Coffee (Large)" to the product that
+	// groups it; zero for products that are not variants
+	ParentID    int
+	VariantName string
+	// Active is false for archived products, which can no longer be sold
+	Active bool
+	// TrackStock is false for products made to order from a recipe, which
+	// only deduct their components
+	TrackStock bool
+	// Unit is the unit of measure the product is sold in, e.g. pcs, kg or m
+	Unit string
+	// Precision is the number of decimal places allowed in a quantity
+	Precision int
+	// ReorderPoint is the stock level at or below which the product is
+	// flagged for reordering; zero disables the alert
+	ReorderPoint    float64
+	ReorderQuantity float64
+}
+
+// IsLowStock reports whether the product is at or below its reorder point
+func (p Product) IsLowStock() bool {
+	return p.TrackStock && p.ReorderPoint > 0 && p.Stock <= p.ReorderPoint
+}
+
+// Margin returns the gross margin as a percentage of the selling price
+func (p Product) Margin() float64 {
+	if p.Price == 0 {
+		return 0
+	}
+	return (p.Price - p.Cost) / p.Price * 100
+}
+
+// SuggestedReorder returns the quantity to order for a low-stock product. It
+// falls back to topping stock back up to the reorder point when no reorder
+// quantity is configured.
+func (p Product) SuggestedReorder() float64 {
+	if p.ReorderQuantity > 0 {
+		return p.ReorderQuantity
+	}
+	return math.Max(p.ReorderPoint-p.Stock, 0)
+}
+
+// RoundQuantity rounds a quantity to the precision configured for the product
+func (p Product) RoundQuantity(quantity float64) float64 {
+	scale := math.Pow(10, float64(p.Precision))
+	return math.Round(quantity*scale) / scale
+}
+
+// FormatQuantity formats a quantity with the product's precision and unit
+func (p Product) FormatQuantity(quantity float64) string {
+	return FormatQuantity(quantity, p.Precision) + " " + p.Unit
+}
+
+// FormatQuantity formats a quantity with the given number of decimal places
+func FormatQuantity(quantity float64, precision int) string {
+	return strconv.FormatFloat(quantity, 'f', precision, 64)
 }
 
 // CartItem represents an item in the shopping cart
 type CartItem struct {
+	Product Product
+	// Package is the packaging unit sold, if not the base unit. Quantity is
+	// then a number of packages.
+	Package   *ProductPackage
+	Quantity  float64
+	Modifiers []ModifierOption
+	// AllowExpired is set once a supervisor approves selling expired lots
+	AllowExpired bool
+}
+
+// UnitPrice returns the price of one unit or package including any modifier
+// price changes
+func (c CartItem) UnitPrice() float64 {
+	price := c.Product.Price
+	if c.Package != nil {
+		price = c.Package.Price
+	}
+	for _, m := range c.Modifiers {
+		price += m.PriceDelta
+	}
+	return price
+}
+
+// Subtotal returns the line total for the cart item
+func (c CartItem) Subtotal() float64 {
+	return c.UnitPrice() * c.Quantity
+}
+
+// BaseQuantity returns the quantity in the product's base unit
+func (c CartItem) BaseQuantity() float64 {
+	if c.Package != nil {
+		return c.Quantity * c.Package.Units
+	}
+	return c.Quantity
+}
+
+// FormatQuantity formats the quantity in the unit or package sold
+func (c CartItem) FormatQuantity() string {
+	if c.Package != nil {
+		return FormatQuantity(c.Quantity, 0) + " " + c.Package.Name
+	}
+	return c.Product.FormatQuantity(c.Quantity)
+}
+
+// SameAs reports whether another item is the same product in the same
+// package with the same modifiers, so the two can share a cart line
+func (c CartItem) SameAs(other CartItem) bool {
+	if c.Product.ID != other.Product.ID || len(c.Modifiers) != len(other.Modifiers) {
+		return false
+	}
+	if (c.Package == nil) != (other.Package == nil) || (c.Package != nil && c.Package.ID != other.Package.ID) {
+		return false
+	}
+	for i := range c.Modifiers {
+		if c.Modifiers[i].ID != other.Modifiers[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+// ProductPackage is a packaging unit holding a fixed number of a product's
+// base unit, with its own barcode and price
+type ProductPackage struct {
+	ID        int
+	ProductID int
+	Name      string
+	Barcode   string
+	Units     float64
+	Price     float64
+}
+
+// FormatInPackage formats a base quantity as whole packages plus the
+// remaining base units, e.g. "2 Carton + 3 pcs"
+func (p Product) FormatInPackage(quantity float64, pkg ProductPackage) string {
+	packages := math.Trunc(quantity / pkg.Units)
+	rest := p.RoundQuantity(quantity - packages*pkg.Units)
+	text := FormatQuantity(packages, 0) + " " + pkg.Name
+	if rest != 0 {
+		text += " + " + p.FormatQuantity(rest)
+	}
+	return text
+}
+
+// RecipeComponent is a quantity of another product used to make one unit of
+// a composite product
+type RecipeComponent struct {
+	Component Product
+	Quantity  float64
+}
+
+// RecipeCost returns the theoretical cost of one unit made from the recipe
+func RecipeCost(components []RecipeComponent) float64 {
+	var cost float64
+	for _, c := range components {
+		cost += c.Component.Cost * c.Quantity
+	}
+	return cost
+}
+
+// ModifierGroup is a set of options offered when a product is sold, such as
+// milk choices or extras
+type ModifierGroup struct {
+	ID        int
+	Name      string
+	MinSelect int
+	MaxSelect int
+	Options   []ModifierOption
+}
+
+// ModifierOption is a single choice in a modifier group
+type ModifierOption struct {
+	ID         int
+	GroupID    int
+	Name       string
+	PriceDelta float64
+}
+
+// Customer represents a registered customer in the loyalty program
+type Customer struct {
+	ID     int
+	Name   string
+	Phone  string
+	Email  string
+	Points int
+}
+
+// Stock movement types
+const (
+	MovementSale       = "sale"
+	MovementRefund     = "refund"
+	MovementReceipt    = "receipt"
+	MovementAdjustment = "adjustment"
+	MovementCount      = "count"
+)
+
+// Payment methods accepted at checkout
+const (
+	PaymentCash     = "cash"
+	PaymentCard     = "card"
+	PaymentQRIS     = "qris"
+	PaymentTransfer = "transfer"
+)
+
+var PaymentMethods = []string{PaymentCash, PaymentCard, PaymentQRIS, PaymentTransfer}
+
+// RefundLine is an invoice item that can be refunded. Quantities are in the
+// product's base unit.
+type RefundLine struct {
+	SaleItemID       int
+	Product          Product
+	QuantitySold     float64
+	QuantityRefunded float64
+	// Quantity is the amount to refund now
+	Quantity  float64
+	UnitPrice float64
+}
+
+// Refundable returns the quantity not yet refunded
+func (l RefundLine) Refundable() float64 {
+	return l.QuantitySold - l.QuantityRefunded
+}
+
+// RegisterReport summarizes register activity for a period. An X-report is a
+// snapshot of the open period; a Z-report closes it and has a number.
+type RegisterReport struct {
+	Number           string
+	Start            time.Time
+	End              time.Time
+	TransactionCount int
+	FirstInvoice     string
+	LastInvoice      string
+	GrossSales       float64
+	Discounts        float64
+	RefundCount      int
+	Refunds          float64
+	RefundTax        float64
+	NetSales         float64
+	TaxCollected     float64
+	TaxByRate        []TaxRateTotal
+	Payments         []PaymentTotal
+}
+
+// TaxRateTotal is the taxable amount and tax collected at one tax rate, net
+// of refunds
+type TaxRateTotal struct {
+	Rate    float64
+	Taxable float64
+	Tax     float64
+}
+
+// PaymentTotal is the amount taken and refunded through one payment method
+type PaymentTotal struct {
+	Method   string
+	Taken    float64
+	Refunded float64
+}
+
+// StockMovement records a single change to a product's stock
+type StockMovement struct {
+	ID        int
+	ProductID int
+	Type      string
+	// Quantity is the signed change, negative when stock leaves the store
+	Quantity   float64
+	StockAfter float64
+	Username   string
+	Reference  string
+	Note       string
+	CreatedAt  time.Time
+}
+
+// Supplier represents a company the store buys stock from
+type Supplier struct {
+	ID          int
+	Name        string
+	ContactName string
+	Phone       string
+	Email       string
+	Address     string
+}
+
+// Purchase order statuses
+const (
+	POStatusOpen      = "open"
+	POStatusPartial   = "partial"
+	POStatusReceived  = "received"
+	POStatusCancelled = "cancelled"
+)
+
+// PurchaseOrder represents an order placed with a supplier
+type PurchaseOrder struct {
+	ID           int
+	Number       string
+	SupplierID   int
+	SupplierName string
+	Status       string
+	ExpectedDate *time.Time
+	Note         string
+	CreatedBy    string
+	CreatedAt    time.Time
+	Lines        []PurchaseOrderLine
+}
+
+// PurchaseOrderLine is a single product ordered on a purchase order
+type PurchaseOrderLine struct {
+	ID               int
+	Product          Product
+	QuantityOrdered  float64
+	QuantityReceived float64
+	UnitCost         float64
+}
+
+// Outstanding returns the quantity still to be delivered
+func (l PurchaseOrderLine) Outstanding() float64 {
+	return math.Max(l.QuantityOrdered-l.QuantityReceived, 0)
+}
+
+// ReceiptLine is the quantity delivered against a purchase order line. A lot
+// number or expiry date puts the delivery in a lot of its own.
+type ReceiptLine struct {
+	PurchaseOrderLineID int
+	Quantity            float64
+	UnitCost            float64
+	LotNumber           string
+	ExpiryDate          *time.Time
+}
+
+// StockLot is a quantity of a product received together, sharing a lot
+// number and expiry date
+type StockLot struct {
+	ID                int
+	Product           Product
+	LotNumber         string
+	ExpiryDate        *time.Time
+	QuantityReceived  float64
+	QuantityRemaining float64
+	ReceivedAt        time.Time
+}
+
+// IsExpired reports whether the lot is past its expiry date on the given day.
+// Stock can still be sold on its expiry date.
+func (l StockLot) IsExpired(day time.Time) bool {
+	if l.ExpiryDate == nil {
+		return false
+	}
+	y, m, d := day.Date()
+	return l.ExpiryDate.Before(time.Date(y, m, d, 0, 0, 0, 0, l.ExpiryDate.Location()))
+}
+
+// PriceChange is an entry in a product's price timeline. Entries with an
+// effective time in the future are scheduled changes.
+type PriceChange struct {
+	ID            int
+	ProductID     int
+	Price         float64
+	EffectiveFrom time.Time
+	CreatedBy     string
+	CreatedAt     time.Time
+}
+
+// Stocktake statuses
+const (
+	StocktakeCounting  = "counting"
+	StocktakePosted    = "posted"
+	StocktakeCancelled = "cancelled"
+)
+
+// Stocktake is a physical count session. Expected quantities are frozen when
+// the session starts so sales during the count do not skew the variance.
+type Stocktake struct {
+	ID         int
+	Name       string
+	Status     string
+	Category   string
+	Shelf      string
+	CreatedBy  string
+	ApprovedBy string
+	CreatedAt  time.Time
+	PostedAt   *time.Time
+	Lines      []StocktakeLine
+}
+
+// StocktakeLine is the expected and counted quantity of one product
+type StocktakeLine struct {
+	ID       int
 	Product  Product
-	Quantity int
+	Expected float64
+	// Counted is nil until the product has been counted
+	Counted   *float64
+	UnitValue float64
+}
+
+// Variance returns counted minus expected, or zero when not yet counted
+func (l StocktakeLine) Variance() float64 {
+	if l.Counted == nil {
+		return 0
+	}
+	return l.Product.RoundQuantity(*l.Counted - l.Expected)
+}
+
+// VarianceValue returns the value of the variance at the line's unit cost
+func (l StocktakeLine) VarianceValue() float64 {
+	return l.Variance() * l.UnitValue
+}
+
+// SalesReport is product sales over a period, valued at the prices and costs
+// recorded when each sale was made
+type SalesReport struct {
+	Range     TimeRange
+	Lines     []SalesReportLine
+	Modifiers []ModifierSalesLine
+}
+
+// Totals returns the revenue and cost of goods sold across all lines
+func (r SalesReport) Totals() (revenue, cost float64) {
+	for _, l := range r.Lines {
+		revenue += l.Revenue
+		cost += l.Cost
+	}
+	return revenue, cost
+}
+
+// SalesReportLine is one product's sales. Quantity is in the product's base
+// unit.
+type SalesReportLine struct {
+	Product  Product
+	Quantity float64
+	Revenue  float64
+	Cost     float64
+}
+
+// GrossProfit returns revenue less cost of goods sold
+func (l SalesReportLine) GrossProfit() float64 {
+	return l.Revenue - l.Cost
+}
+
+// MarginPercent returns gross profit as a percentage of revenue
+func MarginPercent(revenue, cost float64) float64 {
+	if revenue == 0 {
+		return 0
+	}
+	return (revenue - cost) / revenue * 100
+}
+
+// ModifierSalesLine is how often a modifier was chosen and the revenue it
+// added
+type ModifierSalesLine struct {
+	Name     string
+	Quantity float64
+	Revenue  float64
+}
+
+// SalesTrends breaks revenue over a period down by day, by hour of the week
+// and by payment method
+type SalesTrends struct {
+	Daily []DailyTotal
+	// ByHour is revenue by weekday, Sunday first, and hour of day
+	ByHour   [7][24]float64
+	Payments []PaymentTotal
+}
+
+// DailyTotal is the revenue taken on one day
+type DailyTotal struct {
+	Day     time.Time
+	Revenue float64
+}
+
+// Report range presets
+const (
+	RangeToday     = "Today"
+	RangeYesterday = "Yesterday"
+	RangeThisWeek  = "This Week"
+	RangeThisMonth = "This Month"
+	RangeLastMonth = "Last Month"
+	RangeCustom    = "Custom"
+)
+
+var RangePresets = []string{RangeToday, RangeYesterday, RangeThisWeek, RangeThisMonth, RangeLastMonth, RangeCustom}
+
+// TimeRange is a reporting period from the start of its first day up to, but
+// not including, the start of the day after its last day. Days begin at
+// midnight in the store's time zone.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// NewTimeRange returns the range covering the whole of the days from first
+// to last inclusive in loc. Only the dates of first and last are used.
+func NewTimeRange(first, last time.Time, loc *time.Location) TimeRange {
+	return TimeRange{
+		Start: time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc),
+		End:   time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, loc),
+	}
+}
+
+// PresetRange returns the range for a preset as of now. Weeks start on
+// Monday. Custom and unknown presets return today.
+func PresetRange(preset string, now time.Time, loc *time.Location) TimeRange {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch preset {
+	case RangeYesterday:
+		yesterday := today.AddDate(0, 0, -1)
+		return NewTimeRange(yesterday, yesterday, loc)
+	case RangeThisWeek:
+		sinceMonday := (int(today.Weekday()) + 6) % 7
+		return NewTimeRange(today.AddDate(0, 0, -sinceMonday), today, loc)
+	case RangeThisMonth:
+		return NewTimeRange(time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc), today, loc)
+	case RangeLastMonth:
+		firstOfMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc)
+		return NewTimeRange(firstOfMonth.AddDate(0, -1, 0), firstOfMonth.AddDate(0, 0, -1), loc)
+	}
+	return NewTimeRange(today, today, loc)
+}
+
+// LastDay returns the start of the last day in the range
+func (r TimeRange) LastDay() time.Time {
+	return r.End.AddDate(0, 0, -1)
+}
+
+// Days returns the start of each day in the range
+func (r TimeRange) Days() []time.Time {
+	var days []time.Time
+	for day := r.Start; day.Before(r.End); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// String describes the range by its first and last dates
+func (r TimeRange) String() string {
+	first, last := r.Start.Format("2006-01-02"), r.LastDay().Format("2006-01-02")
+	if first == last {
+		return first
+	}
+	return first + " to " + last
+}
+
+// Comparison periods for sales reports
+const (
+	CompareNone     = "No Comparison"
+	ComparePrevious = "Previous Period"
+	CompareLastYear = "Same Period Last Year"
+	CompareCustom   = "Custom Period"
+)
+
+var ComparisonOptions = []string{CompareNone, ComparePrevious, CompareLastYear, CompareCustom}
+
+// Previous returns the range of the same number of days ending where this
+// one starts
+func (r TimeRange) Previous() TimeRange {
+	days := len(r.Days())
+	return TimeRange{Start: r.Start.AddDate(0, 0, -days), End: r.Start}
+}
+
+// LastYear returns the same dates one year earlier
+func (r TimeRange) LastYear() TimeRange {
+	return TimeRange{Start: r.Start.AddDate(-1, 0, 0), End: r.End.AddDate(-1, 0, 0)}
+}
+
+// SalesComparison is a product's sales in a report period and in the period
+// it is compared against
+type SalesComparison struct {
+	Product  Product
+	Current  SalesReportLine
+	Previous SalesReportLine
+}
+
+// CompareSales pairs up each product's sales in two reports. Products sold in
+// either period are included, ordered by current revenue and then by
+// previous revenue.
+func CompareSales(current, previous SalesReport) []SalesComparison {
+	index := map[int]int{}
+	var comparisons []SalesComparison
+	for _, l := range current.Lines {
+		index[l.Product.ID] = len(comparisons)
+		comparisons = append(comparisons, SalesComparison{Product: l.Product, Current: l})
+	}
+	for _, l := range previous.Lines {
+		if i, ok := index[l.Product.ID]; ok {
+			comparisons[i].Previous = l
+			continue
+		}
+		comparisons = append(comparisons, SalesComparison{Product: l.Product, Previous: l})
+	}
+	return comparisons
+}
+
+// PercentChange returns the change from previous to current as a percentage
+// of previous. ok is false when there is nothing to compare against.
+func PercentChange(current, previous float64) (change float64, ok bool) {
+	if previous == 0 {
+		return 0, false
+	}
+	return (current - previous) / math.Abs(previous) * 100, true
+}
+
+// ABC classes rank products by their cumulative share of revenue. Class A
+// products make up the first 80% of revenue, B the next 15% and C the rest.
+const (
+	ABCClassA = "A"
+	ABCClassB = "B"
+	ABCClassC = "C"
+
+	ABCShareA = 80.0
+	ABCShareB = 95.0
+)
+
+// ProductPerformance is a product's sales over a report period with its
+// rankings. Ranks start at 1; products without sales are unranked (0).
+type ProductPerformance struct {
+	SalesReportLine
+	Class        string
+	RevenueRank  int
+	QuantityRank int
+	MarginRank   int
+	// Velocity is the average quantity sold per day of the period
+	Velocity float64
+	LastSold *time.Time
+	// DeadStock is set for stocked products with no sales in the dead stock
+	// window
+	DeadStock bool
+}
+
+// DaysOfCover returns how many days the stock on hand lasts at the current
+// velocity. ok is false when the product is not selling.
+func (p ProductPerformance) DaysOfCover() (days float64, ok bool) {
+	if p.Velocity <= 0 {
+		return 0, false
+	}
+	return p.Product.Stock / p.Velocity, true
+}
+
+// RankPerformance ranks products by revenue, quantity and margin and puts
+// them into ABC classes by revenue. The result is ordered by revenue rank.
+func RankPerformance(lines []ProductPerformance) {
+	rank := func(less func(a, b ProductPerformance) bool, set func(p *ProductPerformance, rank int)) {
+		order := make([]int, 0, len(lines))
+		for i, l := range lines {
+			if l.Quantity > 0 {
+				order = append(order, i)
+			}
+		}
+		sort.SliceStable(order, func(i, j int) bool { return less(lines[order[i]], lines[order[j]]) })
+		for r, i := range order {
+			set(&lines[i], r+1)
+		}
+	}
+	rank(func(a, b ProductPerformance) bool { return a.Quantity > b.Quantity },
+		func(p *ProductPerformance, r int) { p.QuantityRank = r })
+	rank(func(a, b ProductPerformance) bool {
+		return MarginPercent(a.Revenue, a.Cost) > MarginPercent(b.Revenue, b.Cost)
+	}, func(p *ProductPerformance, r int) { p.MarginRank = r })
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Revenue > lines[j].Revenue })
+	var total float64
+	for _, l := range lines {
+		total += l.Revenue
+	}
+	var cumulative float64
+	for i := range lines {
+		if lines[i].Quantity > 0 {
+			lines[i].RevenueRank = i + 1
+		}
+
+		// A product's class is set by the share reached before it is added,
+		// so the product that crosses a boundary stays in the higher class
+		share := 0.0
+		if total > 0 {
+			share = cumulative / total * 100
+		}
+		switch {
+		case lines[i].Revenue <= 0:
+			lines[i].Class = ABCClassC
+		case share < ABCShareA:
+			lines[i].Class = ABCClassA
+		case share < ABCShareB:
+			lines[i].Class = ABCClassB
+		default:
+			lines[i].Class = ABCClassC
+		}
+		cumulative += lines[i].Revenue
+	}
+}
+
+// Shift is a cashier's session at the till. Expected cash is the opening
+// float plus cash taken less cash refunded, worked out when the shift closes.
+type Shift struct {
+	ID           int
+	Cashier      string
+	OpeningFloat float64
+	OpenedAt     time.Time
+	ClosedAt     *time.Time
+	ExpectedCash float64
+	CountedCash  float64
+}
+
+// Variance returns counted cash less expected cash, or zero while the shift
+// is open
+func (s Shift) Variance() float64 {
+	if s.ClosedAt == nil {
+		return 0
+	}
+	return s.CountedCash - s.ExpectedCash
+}
+
+// CashierPerformance is one cashier's activity over a report period
+type CashierPerformance struct {
+	Cashier      string
+	Transactions int
+	GrossSales   float64
+	Items        float64
+	Discounts    float64
+	VoidCount    int
+	VoidAmount   float64
+	RefundCount  int
+	RefundAmount float64
+	Shifts       []Shift
+}
+
+// AverageBasket returns gross sales per transaction
+func (c CashierPerformance) AverageBasket() float64 {
+	if c.Transactions == 0 {
+		return 0
+	}
+	return c.GrossSales / float64(c.Transactions)
+}
+
+// ItemsPerBasket returns the average number of items per transaction
+func (c CashierPerformance) ItemsPerBasket() float64 {
+	if c.Transactions == 0 {
+		return 0
+	}
+	return c.Items / float64(c.Transactions)
+}
+
+// CashVariance returns the total variance of the cashier's closed shifts
+func (c CashierPerformance) CashVariance() float64 {
+	var variance float64
+	for _, s := range c.Shifts {
+		variance += s.Variance()
+	}
+	return variance
+}
+
+// Tax document types
+const (
+	TaxDocumentInvoice = "invoice"
+	TaxDocumentRefund  = "refund"
+)
+
+// TaxDocument is an invoice or refund as it counts toward tax. Refunds carry
+// negative amounts, so summing documents nets them against sales.
+type TaxDocument struct {
+	Date     time.Time
+	Number   string
+	Type     string
+	Invoice  string // the refunded invoice, for a refund
+	Customer string
+	Rate     float64
+	Taxable  float64
+	Tax      float64
+}
+
+// Exempt reports whether the document was charged no tax
+func (d TaxDocument) Exempt() bool {
+	return d.Rate == 0
+}
+
+// TaxRateSummary is tax at one rate over a report period. The embedded totals
+// are net of refunds, which are also given on their own.
+type TaxRateSummary struct {
+	TaxRateTotal
+	Invoices        int
+	Refunds         int
+	RefundedTaxable float64
+	RefundedTax     float64
+}
+
+// Exempt reports whether sales at this rate were charged no tax
+func (s TaxRateSummary) Exempt() bool {
+	return s.Rate == 0
+}
+
+// SummarizeTax totals documents per tax rate, ordered by rate
+func SummarizeTax(documents []TaxDocument) []TaxRateSummary {
+	var summaries []TaxRateSummary
+	byRate := map[float64]int{}
+	for _, d := range documents {
+		i, ok := byRate[d.Rate]
+		if !ok {
+			i = len(summaries)
+			byRate[d.Rate] = i
+			summaries = append(summaries, TaxRateSummary{TaxRateTotal: TaxRateTotal{Rate: d.Rate}})
+		}
+		s := &summaries[i]
+		s.Taxable += d.Taxable
+		s.Tax += d.Tax
+		if d.Type == TaxDocumentRefund {
+			s.Refunds++
+			s.RefundedTaxable -= d.Taxable
+			s.RefundedTax -= d.Tax
+		} else {
+			s.Invoices++
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Rate < summaries[j].Rate })
+	return summaries
 }
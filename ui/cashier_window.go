@@ -4,8 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -23,6 +24,11 @@ type CashierWindow struct {
 	window    fyne.Window
 	database  *sql.DB
 	cartItems []types.CartItem
+	customer  *types.Customer
+	// stock and recipes are used to check availability of composite products
+	stock    map[int]float64
+	recipes  map[int]map[int]float64
+	packages map[int][]types.ProductPackage
 }
 
 func NewCashierWindow(window fyne.Window, database *sql.DB) *CashierWindow {
@@ -34,17 +40,43 @@ func NewCashierWindow(window fyne.Window, database *sql.DB) *CashierWindow {
 }
 
 func (c *CashierWindow) Load() error {
+	if err := db.ApplyScheduledPrices(c.database); err != nil {
+		return fmt.Errorf("could not apply scheduled prices: %v", err)
+	}
+
 	products, err := db.GetProducts(c.database)
 	if err != nil {
 		return fmt.Errorf("could not fetch products: %v", err)
 	}
 
-	content := c.createCashierContent(products)
+	c.recipes, err = db.GetRecipeQuantities(c.database)
+	if err != nil {
+		return fmt.Errorf("could not fetch recipes: %v", err)
+	}
+	c.packages, err = db.GetPackages(c.database)
+	if err != nil {
+		return fmt.Errorf("could not fetch packaging units: %v", err)
+	}
+	c.stock = map[int]float64{}
+	for _, p := range products {
+		c.stock[p.ID] = p.Stock
+	}
+
+	groups, err := db.GetModifierGroups(c.database)
+	if err != nil {
+		return fmt.Errorf("could not fetch modifiers: %v", err)
+	}
+	productGroups, err := db.GetProductModifierGroupIDs(c.database)
+	if err != nil {
+		return fmt.Errorf("could not fetch modifiers: %v", err)
+	}
+
+	content := c.createCashierContent(products, newModifierLookup(groups, productGroups))
 	c.window.SetContent(content)
 	return nil
 }
 
-func (c *CashierWindow) createCashierContent(products []types.Product) fyne.CanvasObject {
+func (c *CashierWindow) createCashierContent(products []types.Product, modifiers modifierLookup) fyne.CanvasObject {
 	// Back button
 	backButton := widget.NewButton("Back to Menu", func() {
 		mainWindow := NewMainWindow(c.window, c.database)
@@ -57,6 +89,8 @@ func (c *CashierWindow) createCashierContent(products []types.Product) fyne.Canv
 	header := container.NewHBox(
 		backButton,
 		widget.NewLabel("Cashier System"),
+		layout.NewSpacer(),
+		widget.NewLabel(fmt.Sprintf("Cashier: %s", currentUser)),
 	)
 
 	// Cart display
@@ -68,63 +102,243 @@ func (c *CashierWindow) createCashierContent(products []types.Product) fyne.Canv
 		var total float64
 		cartText := ""
 		for _, item := range c.cartItems {
-			subtotal := item.Product.Price * float64(item.Quantity)
-			cartText += fmt.Sprintf("%s x%d: Rp%.2f\n",
-				item.Product.Name, item.Quantity, subtotal)
+			subtotal := item.Subtotal()
+			cartText += fmt.Sprintf("%s x%s: Rp%.2f\n",
+				item.Product.Name, item.FormatQuantity(), subtotal)
+			for _, m := range item.Modifiers {
+				cartText += fmt.Sprintf("  + %s\n", m.Name)
+			}
 			total += subtotal
 		}
 		cartDisplay.SetText(cartText)
 		totalLabel.SetText(fmt.Sprintf("Total: Rp%.2f", total))
 	}
+	// The cart is kept when the screen is reloaded
+	updateCart()
+
+	productsByID := map[int]types.Product{}
+	for _, product := range products {
+		productsByID[product.ID] = product
+	}
+
+	addLine := func(newItem types.CartItem, allowExpired bool) {
+		prod := newItem.Product
+
+		// Add item to cart, merging lines with the same package and modifiers
+		found := false
+		for i, item := range c.cartItems {
+			if item.SameAs(newItem) {
+				c.cartItems[i].Quantity = prod.RoundQuantity(item.Quantity + newItem.Quantity)
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.cartItems = append(c.cartItems, newItem)
+		}
+
+		// An approved override covers every line of the product
+		if allowExpired {
+			for i := range c.cartItems {
+				if c.cartItems[i].Product.ID == prod.ID {
+					c.cartItems[i].AllowExpired = true
+				}
+			}
+		}
+		updateCart()
+	}
+
+	addToCart := func(prod types.Product, pkg *types.ProductPackage, quantity float64, options []types.ModifierOption) {
+		// A price change may have come due since the till was opened; lines
+		// already in the cart move to the new price too
+		price, err := db.GetCurrentPrice(c.database, prod.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to look up price: %v", err), c.window)
+			return
+		}
+		prod.Price = price
+		for i := range c.cartItems {
+			if c.cartItems[i].Product.ID == prod.ID {
+				c.cartItems[i].Product.Price = price
+			}
+		}
+
+		newItem := types.CartItem{Product: prod, Package: pkg, Quantity: quantity, Modifiers: options}
+		quantity = newItem.BaseQuantity()
+
+		approved := false
+		for _, item := range c.cartItems {
+			if item.Product.ID == prod.ID {
+				approved = approved || item.AllowExpired
+			}
+		}
+
+		if quantity > c.availableToAdd(prod) {
+			dialog := widget.NewLabel(fmt.Sprintf("Not enough stock for %s", prod.Name))
+			popup := widget.NewModalPopUp(dialog, c.window.Canvas())
+			popup.Show()
+			return
+		}
+
+		// Stock in expired lots needs a supervisor before it can be sold,
+		// whether it is sold itself or used by a recipe
+		needed := map[int]float64{}
+		if prod.TrackStock {
+			needed[prod.ID] = quantity
+		}
+		for componentID, perUnit := range c.recipes[prod.ID] {
+			needed[componentID] += quantity * perUnit
+		}
+		for productID, need := range needed {
+			if approved {
+				break
+			}
+			expired, err := db.GetExpiredQuantity(c.database, productID)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to check expiry: %v", err), c.window)
+				return
+			}
+			if expired > 0 && c.cartUses(productID)+need > c.stock[productID]-expired {
+				c.showExpiredOverrideDialog(prod, productsByID[productID], func() {
+					addLine(newItem, true)
+				})
+				return
+			}
+		}
+
+		addLine(newItem, approved)
+	}
+
+	// Selling a product asks for its modifiers, then for the quantity of
+	// weighed and measured goods sold in their base unit
+	sellProduct := func(prod types.Product, pkg *types.ProductPackage) {
+		c.showModifierDialog(prod, modifiers.groupsFor(prod), func(options []types.ModifierOption) {
+			if pkg == nil && prod.Precision > 0 {
+				c.showQuantityDialog(prod, func(quantity float64) {
+					addToCart(prod, nil, quantity, options)
+				})
+				return
+			}
+			addToCart(prod, pkg, 1, options)
+		})
+	}
+
+	// Products with packaging units first ask which unit is being sold
+	chooseAndSell := func(prod types.Product) {
+		if len(c.packages[prod.ID]) == 0 {
+			sellProduct(prod, nil)
+			return
+		}
+		c.showPackageDialog(prod, c.packages[prod.ID], func(pkg *types.ProductPackage) {
+			sellProduct(prod, pkg)
+		})
+	}
+
+	// Scanning a SKU sells the base unit; scanning a package barcode sells
+	// the package
+	scanEntry := widget.NewEntry()
+	scanEntry.SetPlaceHolder("Scan barcode or SKU")
+	scanEntry.OnSubmitted = func(code string) {
+		scanEntry.SetText("")
+		if code == "" {
+			return
+		}
+		productID, pkg, err := db.FindByBarcode(c.database, code)
+		if err == sql.ErrNoRows {
+			dialog.ShowError(fmt.Errorf("no product found for %s", code), c.window)
+			return
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to look up barcode: %v", err), c.window)
+			return
+		}
+		prod, ok := productsByID[productID]
+		if !ok {
+			dialog.ShowError(fmt.Errorf("no product found for %s", code), c.window)
+			return
+		}
+		sellProduct(prod, pkg)
+	}
+
+	// Product list, with variants grouped under their parent product
+	variants := map[int][]types.Product{}
+	for _, product := range products {
+		if product.ParentID != 0 {
+			variants[product.ParentID] = append(variants[product.ParentID], product)
+		}
+	}
 
-	// Product list
 	productList := container.NewVBox()
 	for _, product := range products {
 		prod := product // Create a new variable to avoid closure issues
-		button := widget.NewButton(
-			fmt.Sprintf("%s - Rp%.2f (Stock: %d)", prod.Name, prod.Price, prod.Stock),
-			func() {
-				// Check stock before adding
-				currentQty := 0
-				for _, item := range c.cartItems {
-					if item.Product.Name == prod.Name {
-						currentQty = item.Quantity
-						break
-					}
-				}
+		if prod.ParentID != 0 {
+			continue
+		}
 
-				if currentQty >= prod.Stock {
-					dialog := widget.NewLabel(fmt.Sprintf("Not enough stock for %s", prod.Name))
-					popup := widget.NewModalPopUp(dialog, c.window.Canvas())
-					popup.Show()
-					return
-				}
+		if choices := variants[prod.ID]; len(choices) > 0 {
+			button := widget.NewButton(
+				fmt.Sprintf("%s - %d variants", prod.Name, len(choices)),
+				func() {
+					c.showVariantDialog(prod, choices, chooseAndSell)
+				},
+			)
+			productList.Add(button)
+			continue
+		}
 
-				// Add item to cart
-				found := false
-				for i, item := range c.cartItems {
-					if item.Product.Name == prod.Name {
-						c.cartItems[i].Quantity++
-						found = true
-						break
-					}
-				}
-				if !found {
-					c.cartItems = append(c.cartItems, types.CartItem{
-						Product:  prod,
-						Quantity: 1,
-					})
-				}
-				updateCart()
+		stockText := fmt.Sprintf("Stock: %s", prod.FormatQuantity(prod.Stock))
+		if !prod.TrackStock {
+			stockText = fmt.Sprintf("Can make: %s", prod.FormatQuantity(math.Floor(c.availableToAdd(prod))))
+		}
+		button := widget.NewButton(
+			fmt.Sprintf("%s - Rp%.2f/%s (%s)", prod.Name, prod.Price, prod.Unit, stockText),
+			func() {
+				chooseAndSell(prod)
 			},
 		)
 		productList.Add(button)
 	}
 
+	// Customer attached to the sale
+	customerLabel := widget.NewLabel("Customer: Walk-in")
+	updateCustomer := func() {
+		if c.customer == nil {
+			customerLabel.SetText("Customer: Walk-in")
+			return
+		}
+		customerLabel.SetText(fmt.Sprintf("Customer: %s (%d pts)", c.customer.Name, c.customer.Points))
+	}
+	updateCustomer()
+
+	customerButton := widget.NewButton("Find Customer", func() {
+		c.showCustomerLookupDialog(updateCustomer)
+	})
+	removeCustomerButton := widget.NewButton("Remove Customer", func() {
+		c.customer = nil
+		updateCustomer()
+	})
+
 	// Cart buttons
 	clearButton := widget.NewButton("Clear Cart", func() {
+		// Clearing a cart with items in it voids the transaction
+		if len(c.cartItems) > 0 {
+			if err := db.RecordVoid(c.database, currentUser, c.cartItems); err != nil {
+				dialog.ShowError(fmt.Errorf("error recording void: %v", err), c.window)
+				return
+			}
+		}
 		c.cartItems = []types.CartItem{}
+		c.customer = nil
 		updateCart()
+		updateCustomer()
+	})
+
+	shiftButton := widget.NewButton("Shift", func() {
+		c.showShiftDialog()
+	})
+
+	refundButton := widget.NewButton("Refund", func() {
+		c.showRefundLookupDialog()
 	})
 
 	checkoutButton := widget.NewButton("Checkout", func() {
@@ -134,7 +348,7 @@ func (c *CashierWindow) createCashierContent(products []types.Product) fyne.Canv
 
 		var subtotal float64
 		for _, item := range c.cartItems {
-			subtotal += item.Product.Price * float64(item.Quantity)
+			subtotal += item.Subtotal()
 		}
 
 		settings, err := db.GetSettings(c.database)
@@ -143,15 +357,13 @@ func (c *CashierWindow) createCashierContent(products []types.Product) fyne.Canv
 			return
 		}
 
-		taxAmount := subtotal * (settings.TaxPercentage / 100)
-		total := subtotal + taxAmount
-
-		c.showCheckoutDialog(subtotal, taxAmount, total)
+		c.showCheckoutDialog(subtotal, settings)
 	})
 
 	// Layout setup
 	productSection := container.NewVBox(
 		widget.NewLabel("Products"),
+		scanEntry,
 		productList,
 	)
 
@@ -159,7 +371,9 @@ func (c *CashierWindow) createCashierContent(products []types.Product) fyne.Canv
 		widget.NewLabel("Shopping Cart"),
 		cartDisplay,
 		totalLabel,
-		container.NewHBox(clearButton, checkoutButton),
+		customerLabel,
+		container.NewHBox(customerButton, removeCustomerButton),
+		container.NewHBox(clearButton, shiftButton, refundButton, checkoutButton),
 	)
 
 	// Main content split
@@ -172,14 +386,148 @@ func (c *CashierWindow) createCashierContent(products []types.Product) fyne.Canv
 	return content
 }
 
-func (c *CashierWindow) generateInvoice(invoiceNumber string, payment, change float64) string {
+func (c *CashierWindow) showQuantityDialog(product types.Product, onConfirm func(quantity float64)) {
+	quantityEntry := widget.NewEntry()
+	quantityEntry.SetPlaceHolder(fmt.Sprintf("Quantity in %s", product.Unit))
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(fmt.Sprintf("Quantity (%s)", product.Unit), quantityEntry),
+	}
+
+	dialog.ShowForm(product.Name, "Add", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			quantity, err := strconv.ParseFloat(quantityEntry.Text, 64)
+			if err != nil || quantity <= 0 {
+				dialog.ShowError(fmt.Errorf("invalid quantity"), c.window)
+				return
+			}
+
+			quantity = product.RoundQuantity(quantity)
+			if quantity == 0 {
+				dialog.ShowError(fmt.Errorf("quantity is below the smallest allowed amount"), c.window)
+				return
+			}
+
+			onConfirm(quantity)
+		}, c.window)
+}
+
+// showExpiredOverrideDialog asks for the supervisor PIN before a product that
+// draws on expired stock of itself or of a recipe component is added to the
+// cart
+func (c *CashierWindow) showExpiredOverrideDialog(product, expired types.Product, onApproved func()) {
+	pinEntry := widget.NewPasswordEntry()
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Supervisor PIN", pinEntry),
+	}
+
+	title := fmt.Sprintf("%s is past its expiry date", product.Name)
+	if expired.ID != product.ID {
+		title = fmt.Sprintf("%s uses %s that is past its expiry date", product.Name, expired.Name)
+	}
+
+	dialog.ShowForm(title, "Override", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			ok, err := db.VerifySupervisorPIN(c.database, pinEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to verify PIN: %v", err), c.window)
+				return
+			}
+			if !ok {
+				dialog.ShowError(fmt.Errorf("incorrect supervisor PIN"), c.window)
+				return
+			}
+
+			onApproved()
+		}, c.window)
+}
+
+func (c *CashierWindow) showCustomerLookupDialog(onAttached func()) {
+	phoneEntry := widget.NewEntry()
+	phoneEntry.SetPlaceHolder("Customer phone number")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Phone", phoneEntry),
+	}
+
+	dialog.ShowForm("Find Customer", "Find", "Cancel", items,
+		func(confirm bool) {
+			if !confirm || phoneEntry.Text == "" {
+				return
+			}
+
+			customer, err := db.GetCustomerByPhone(c.database, phoneEntry.Text)
+			if err == sql.ErrNoRows {
+				c.showAddCustomerDialog(phoneEntry.Text, onAttached)
+				return
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to look up customer: %v", err), c.window)
+				return
+			}
+
+			c.customer = &customer
+			onAttached()
+		}, c.window)
+}
+
+func (c *CashierWindow) showAddCustomerDialog(phone string, onAttached func()) {
+	nameEntry := widget.NewEntry()
+	phoneEntry := widget.NewEntry()
+	phoneEntry.SetText(phone)
+	emailEntry := widget.NewEntry()
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Phone", phoneEntry),
+		widget.NewFormItem("Email", emailEntry),
+	}
+
+	dialog.ShowForm("New Customer", "Add", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			if nameEntry.Text == "" || phoneEntry.Text == "" {
+				dialog.ShowError(fmt.Errorf("name and phone are required"), c.window)
+				return
+			}
+
+			customer := types.Customer{
+				Name:  nameEntry.Text,
+				Phone: phoneEntry.Text,
+				Email: emailEntry.Text,
+			}
+
+			id, err := db.AddCustomer(c.database, customer)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to add customer: %v", err), c.window)
+				return
+			}
+			customer.ID = id
+
+			c.customer = &customer
+			onAttached()
+		}, c.window)
+}
+
+func (c *CashierWindow) generateInvoice(invoiceNumber string, totals db.InvoiceTotals, paymentMethod string, payment, change float64) string {
 	settings, err := db.GetSettings(c.database)
 	if err != nil {
 		log.Printf("Error getting settings: %v", err)
 		return ""
 	}
 
-	var subtotal float64
 	invoice := "\n=================================\n"
 	invoice += fmt.Sprintf("           %s          \n", settings.StoreName)
 	invoice += "=================================\n"
@@ -187,25 +535,40 @@ func (c *CashierWindow) generateInvoice(invoiceNumber string, payment, change fl
 	invoice += fmt.Sprintf("Date: %s\n", time.Now().Format("2006-01-02 15:04:05"))
 	invoice += fmt.Sprintf("Address: %s\n", settings.StoreAddress)
 	invoice += fmt.Sprintf("Phone: %s\n", settings.StorePhone)
+	if c.customer != nil {
+		invoice += fmt.Sprintf("Customer: %s\n", c.customer.Name)
+	}
 	invoice += "---------------------------------\n"
 	invoice += "Items:\n"
 
 	for _, item := range c.cartItems {
-		itemTotal := item.Product.Price * float64(item.Quantity)
-		invoice += fmt.Sprintf("%-20s x%d\n", item.Product.Name, item.Quantity)
-		invoice += fmt.Sprintf("    @Rp%-14.2f Rp%.2f\n", item.Product.Price, itemTotal)
-		subtotal += itemTotal
+		itemTotal := item.Subtotal()
+		invoice += fmt.Sprintf("%-20s x%s\n", item.Product.Name, item.FormatQuantity())
+		for _, m := range item.Modifiers {
+			invoice += fmt.Sprintf("  + %-17s Rp%.2f\n", m.Name, m.PriceDelta)
+		}
+		invoice += fmt.Sprintf("    @Rp%-14.2f Rp%.2f\n", item.UnitPrice(), itemTotal)
 	}
 
-	taxAmount := subtotal * (settings.TaxPercentage / 100)
-	total := subtotal + taxAmount
-
 	invoice += "---------------------------------\n"
-	invoice += fmt.Sprintf("Subtotal:       Rp%.2f\n", subtotal)
-	invoice += fmt.Sprintf("Tax (%.1f%%):     Rp%.2f\n", settings.TaxPercentage, taxAmount)
-	invoice += fmt.Sprintf("Total:          Rp%.2f\n", total)
+	invoice += fmt.Sprintf("Subtotal:       Rp%.2f\n", totals.Subtotal)
+	if totals.Discount > 0 {
+		invoice += fmt.Sprintf("Points Disc.:  -Rp%.2f\n", totals.Discount)
+	}
+	invoice += fmt.Sprintf("Tax (%.1f%%):     Rp%.2f\n", settings.TaxPercentage, totals.TaxAmount)
+	invoice += fmt.Sprintf("Total:          Rp%.2f\n", totals.Total)
+	if totals.PointsPayment > 0 {
+		invoice += fmt.Sprintf("Paid w/ Points: Rp%.2f\n", totals.PointsPayment)
+	}
+	invoice += fmt.Sprintf("Paid By:        %s\n", strings.ToUpper(paymentMethod))
 	invoice += fmt.Sprintf("Payment:        Rp%.2f\n", payment)
 	invoice += fmt.Sprintf("Change:         Rp%.2f\n", change)
+	if c.customer != nil {
+		invoice += "---------------------------------\n"
+		invoice += fmt.Sprintf("Points Redeemed: %d\n", totals.PointsRedeemed)
+		invoice += fmt.Sprintf("Points Earned:   %d\n", totals.PointsEarned)
+		invoice += fmt.Sprintf("Points Balance:  %d\n", totals.PointsBalance)
+	}
 	invoice += "=================================\n"
 	invoice += "          Thank You!             \n"
 	invoice += "=================================\n"
@@ -214,37 +577,12 @@ func (c *CashierWindow) generateInvoice(invoiceNumber string, payment, change fl
 }
 
 func (c *CashierWindow) printInvoice(invoice string) {
-	// TODO: Add printer configuration from settings
-	// Example structure:
-	// printerConfig, err := db.GetPrinterSettings(c.database)
-	// if err != nil {
-	//     dialog.ShowError(fmt.Errorf("failed to get printer settings: %v", err), c.window)
-	//     return
-	// }
-
-	// TODO: Implement actual printer integration
-	// Common options include:
-	// 1. ESC/POS for thermal receipt printers
-	// 2. CUPS for Unix-like systems
-	// 3. Windows Printer API for Windows systems
-	// Example:
-	// err := printer.Print(printerConfig, invoice)
-
-	// Temporary solution: save to file
-	filename := fmt.Sprintf("invoice_%s.txt", time.Now().Format("20060102150405"))
-	err := os.WriteFile(filename, []byte(invoice), 0644)
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to save invoice: %v", err), c.window)
-		return
-	}
-
-	dialog.ShowInformation("Success",
-		fmt.Sprintf("Invoice saved to %s", filename),
-		c.window,
-	)
+	printDocument(c.window, "invoice", invoice)
 }
 
-func (c *CashierWindow) showCheckoutDialog(subtotal, taxAmount, total float64) {
+func (c *CashierWindow) showCheckoutDialog(subtotal float64, settings db.Settings) {
+	totals := db.CalculateInvoiceTotals(settings, subtotal, 0)
+
 	// Get theme colors
 	bgColor := theme.BackgroundColor()
 	textColor := theme.ForegroundColor()
@@ -263,20 +601,34 @@ func (c *CashierWindow) showCheckoutDialog(subtotal, taxAmount, total float64) {
 
 	// Summary section with themed background
 	summaryBg := canvas.NewRectangle(bgColor)
+	discountText := createThemedLabel("", fyne.TextAlignTrailing, amountStyle)
+	taxText := createThemedLabel("", fyne.TextAlignTrailing, amountStyle)
+	totalText := createThemedLabel("", fyne.TextAlignTrailing, amountStyle)
+	dueText := createThemedLabel("", fyne.TextAlignTrailing, amountStyle)
 	summaryContent := container.NewVBox(
 		container.NewGridWithColumns(2,
 			createThemedLabel("Subtotal:", fyne.TextAlignLeading, titleStyle),
 			createThemedLabel(fmt.Sprintf("Rp%.2f", subtotal), fyne.TextAlignTrailing, amountStyle),
 		),
 		widget.NewSeparator(),
+		container.NewGridWithColumns(2,
+			createThemedLabel("Points Discount:", fyne.TextAlignLeading, titleStyle),
+			discountText,
+		),
+		widget.NewSeparator(),
 		container.NewGridWithColumns(2,
 			createThemedLabel("Tax Amount:", fyne.TextAlignLeading, titleStyle),
-			createThemedLabel(fmt.Sprintf("Rp%.2f", taxAmount), fyne.TextAlignTrailing, amountStyle),
+			taxText,
 		),
 		widget.NewSeparator(),
 		container.NewGridWithColumns(2,
 			createThemedLabel("Total Amount:", fyne.TextAlignLeading, titleStyle),
-			createThemedLabel(fmt.Sprintf("Rp%.2f", total), fyne.TextAlignTrailing, amountStyle),
+			totalText,
+		),
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2,
+			createThemedLabel("Amount Due:", fyne.TextAlignLeading, titleStyle),
+			dueText,
 		),
 	)
 
@@ -295,16 +647,18 @@ func (c *CashierWindow) showCheckoutDialog(subtotal, taxAmount, total float64) {
 	paymentEntry.SetPlaceHolder("Enter payment amount")
 
 	changeLabel := createThemedLabel("Change: Rp0.00", fyne.TextAlignTrailing, amountStyle)
+	changeBreakdown := widget.NewTextGrid()
 
 	// Calculate change in real-time
 	paymentEntry.OnChanged = func(value string) {
+		changeBreakdown.SetText("")
 		payment, err := strconv.ParseFloat(value, 64)
 		if err != nil {
 			changeLabel.Text = "Change: Invalid amount"
 			changeLabel.Refresh()
 			return
 		}
-		change := payment - total
+		change := payment - totals.AmountDue
 		if change < 0 {
 			changeLabel.Text = "Insufficient payment"
 			changeLabel.Color = theme.ErrorColor()
@@ -314,16 +668,84 @@ func (c *CashierWindow) showCheckoutDialog(subtotal, taxAmount, total float64) {
 		changeLabel.Text = fmt.Sprintf("Change: Rp%.2f", change)
 		changeLabel.Color = textColor
 		changeLabel.Refresh()
+		changeBreakdown.SetText(formatChangeBreakdown(change))
+	}
+
+	// Non-cash payments are taken for exactly the amount due
+	methodSelect := widget.NewSelect(types.PaymentMethods, func(method string) {
+		if method != types.PaymentCash {
+			paymentEntry.SetText(strconv.FormatFloat(totals.AmountDue, 'f', 2, 64))
+			paymentEntry.Disable()
+		} else {
+			paymentEntry.Enable()
+		}
+	})
+
+	// Quick tender buttons for the exact amount and the next likely banknotes
+	tenderButtons := container.NewGridWithColumns(3)
+
+	refreshTotals := func() {
+		discountText.Text = fmt.Sprintf("-Rp%.2f", totals.Discount)
+		taxText.Text = fmt.Sprintf("Rp%.2f", totals.TaxAmount)
+		totalText.Text = fmt.Sprintf("Rp%.2f", totals.Total)
+		dueText.Text = fmt.Sprintf("Rp%.2f", totals.AmountDue)
+		for _, text := range []*canvas.Text{discountText, taxText, totalText, dueText} {
+			text.Refresh()
+		}
+
+		tenderButtons.RemoveAll()
+		for _, amount := range quickTenderAmounts(totals.AmountDue) {
+			tender := amount
+			label := fmt.Sprintf("Rp%.0f", tender)
+			if tender == totals.AmountDue {
+				label = "Exact"
+			}
+			tenderButtons.Add(widget.NewButton(label, func() {
+				paymentEntry.SetText(strconv.FormatFloat(tender, 'f', 2, 64))
+			}))
+		}
+		if methodSelect.Selected != types.PaymentCash {
+			paymentEntry.SetText(strconv.FormatFloat(totals.AmountDue, 'f', 2, 64))
+		}
+		paymentEntry.OnChanged(paymentEntry.Text)
+	}
+
+	// Loyalty redemption, available once a customer with points is attached
+	pointsEntry := widget.NewEntry()
+	pointsEntry.SetPlaceHolder("Points to redeem")
+	pointsEntry.OnChanged = func(value string) {
+		points, err := strconv.Atoi(value)
+		if err != nil || points < 0 {
+			points = 0
+		}
+		totals = db.CalculateInvoiceTotals(settings, subtotal, min(points, c.customer.Points))
+		refreshTotals()
 	}
 
 	paymentBg := canvas.NewRectangle(bgColor)
-	paymentContent := container.NewVBox(
+	paymentObjects := []fyne.CanvasObject{
 		createThemedLabel("Payment Details", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+	}
+	if c.customer != nil && settings.LoyaltyEnabled && c.customer.Points > 0 {
+		paymentObjects = append(paymentObjects,
+			createThemedLabel(fmt.Sprintf("Redeem Points (%d available, Rp%.0f each):",
+				c.customer.Points, settings.LoyaltyPointValue), fyne.TextAlignLeading, titleStyle),
+			pointsEntry,
+		)
+	}
+	paymentObjects = append(paymentObjects,
+		createThemedLabel("Payment Method:", fyne.TextAlignLeading, titleStyle),
+		methodSelect,
 		createThemedLabel("Payment Amount:", fyne.TextAlignLeading, titleStyle),
 		paymentEntry,
+		tenderButtons,
 		widget.NewSeparator(),
 		changeLabel,
+		changeBreakdown,
 	)
+	paymentContent := container.NewVBox(paymentObjects...)
+	methodSelect.SetSelected(types.PaymentCash)
+	refreshTotals()
 
 	paymentCard := container.NewMax(
 		paymentBg,
@@ -334,10 +756,10 @@ func (c *CashierWindow) showCheckoutDialog(subtotal, taxAmount, total float64) {
 	cartBg := canvas.NewRectangle(bgColor)
 	cartText := "Items:\n"
 	for _, item := range c.cartItems {
-		cartText += fmt.Sprintf("- %s x%d (Rp%.2f)\n",
+		cartText += fmt.Sprintf("- %s x%s (Rp%.2f)\n",
 			item.Product.Name,
-			item.Quantity,
-			item.Product.Price*float64(item.Quantity))
+			item.FormatQuantity(),
+			item.Subtotal())
 	}
 
 	cartContent := container.NewVBox(
@@ -358,12 +780,12 @@ func (c *CashierWindow) showCheckoutDialog(subtotal, taxAmount, total float64) {
 			return
 		}
 
-		if payment < total {
+		if payment < totals.AmountDue {
 			dialog.ShowError(fmt.Errorf("insufficient payment"), c.window)
 			return
 		}
 
-		c.processTransaction(payment, total)
+		c.processTransaction(payment, totals.PointsRedeemed, methodSelect.Selected)
 	})
 	processBtn.Importance = widget.HighImportance
 
@@ -404,11 +826,19 @@ func (c *CashierWindow) showCheckoutDialog(subtotal, taxAmount, total float64) {
 	dialog.Show()
 }
 
-func (c *CashierWindow) processTransaction(payment, total float64) {
-	change := payment - total
+func (c *CashierWindow) processTransaction(payment float64, pointsRedeemed int, paymentMethod string) {
+	customerID := 0
+	if c.customer != nil {
+		customerID = c.customer.ID
+	}
 
 	// Process the sale
-	saleID, err := db.SaveSale(c.database, c.cartItems)
+	saleID, err := db.SaveSale(c.database, c.cartItems, customerID, currentUser)
+	if err == db.ErrPriceChanged {
+		c.repriceCart()
+		dialog.ShowError(err, c.window)
+		return
+	}
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("error processing sale: %v", err), c.window)
 		return
@@ -422,14 +852,15 @@ func (c *CashierWindow) processTransaction(payment, total float64) {
 	}
 
 	// Save invoice
-	err = db.SaveInvoice(c.database, saleID, invoiceNumber, payment, change)
+	totals, err := db.SaveInvoice(c.database, saleID, invoiceNumber, payment, pointsRedeemed, paymentMethod)
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("error saving invoice: %v", err), c.window)
 		return
 	}
+	change := payment - totals.AmountDue
 
 	// Generate and show invoice
-	invoice := c.generateInvoice(invoiceNumber, payment, change)
+	invoice := c.generateInvoice(invoiceNumber, totals, paymentMethod, payment, change)
 
 	// Show invoice dialog with print option
 	printBtn := widget.NewButton("Print Invoice", func() {
@@ -470,5 +901,417 @@ func (c *CashierWindow) processTransaction(payment, total float64) {
 
 	// Clear cart and refresh
 	c.cartItems = []types.CartItem{}
+	c.customer = nil
 	c.Load()
 }
+
+// repriceCart moves every cart line to its product's current price and
+// reloads the screen to show the new total
+func (c *CashierWindow) repriceCart() {
+	for i := range c.cartItems {
+		price, err := db.GetCurrentPrice(c.database, c.cartItems[i].Product.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to look up price: %v", err), c.window)
+			return
+		}
+		c.cartItems[i].Product.Price = price
+	}
+	if err := c.Load(); err != nil {
+		dialog.ShowError(err, c.window)
+	}
+}
+
+// showShiftDialog opens a shift with a cash float, or closes the open shift
+// with a count of the drawer and shows the variance
+func (c *CashierWindow) showShiftDialog() {
+	shift, err := db.GetOpenShift(c.database, currentUser)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("error loading shift: %v", err), c.window)
+		return
+	}
+
+	cashEntry := widget.NewEntry()
+	if shift == nil {
+		cashEntry.SetPlaceHolder("Cash in drawer at start")
+		items := []*widget.FormItem{
+			widget.NewFormItem("Opening Float", cashEntry),
+		}
+		dialog.ShowForm("Open Shift", "Open", "Cancel", items, func(confirm bool) {
+			if !confirm {
+				return
+			}
+			openingFloat, err := strconv.ParseFloat(cashEntry.Text, 64)
+			if err != nil || openingFloat < 0 {
+				dialog.ShowError(fmt.Errorf("invalid opening float"), c.window)
+				return
+			}
+			if err := db.OpenShift(c.database, currentUser, openingFloat); err != nil {
+				dialog.ShowError(fmt.Errorf("error opening shift: %v", err), c.window)
+				return
+			}
+			dialog.ShowInformation("Shift Opened", fmt.Sprintf("Shift opened with Rp%.2f float", openingFloat), c.window)
+		}, c.window)
+		return
+	}
+
+	// The expected amount is only revealed after counting, so the count is blind
+	cashEntry.SetPlaceHolder("Cash counted in drawer")
+	items := []*widget.FormItem{
+		widget.NewFormItem("Opened", widget.NewLabel(shift.OpenedAt.Format("2006-01-02 15:04"))),
+		widget.NewFormItem("Counted Cash", cashEntry),
+	}
+	dialog.ShowForm("Close Shift", "Close Shift", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		counted, err := strconv.ParseFloat(cashEntry.Text, 64)
+		if err != nil || counted < 0 {
+			dialog.ShowError(fmt.Errorf("invalid cash count"), c.window)
+			return
+		}
+		closed, err := db.CloseShift(c.database, currentUser, counted)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error closing shift: %v", err), c.window)
+			return
+		}
+		dialog.ShowInformation("Shift Closed", fmt.Sprintf(
+			"Opening float: Rp%.2f\nExpected cash: Rp%.2f\nCounted cash:  Rp%.2f\nVariance:      Rp%.2f",
+			closed.OpeningFloat, closed.ExpectedCash, closed.CountedCash, closed.Variance()), c.window)
+	}, c.window)
+}
+
+// showRefundLookupDialog asks for the invoice to refund
+func (c *CashierWindow) showRefundLookupDialog() {
+	invoiceEntry := widget.NewEntry()
+	invoiceEntry.SetPlaceHolder("Invoice number")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Invoice", invoiceEntry),
+	}
+
+	dialog.ShowForm("Refund", "Find", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			inv, err := db.GetRefundableInvoice(c.database, strings.TrimSpace(invoiceEntry.Text))
+			if err == sql.ErrNoRows {
+				dialog.ShowError(fmt.Errorf("invoice %s not found", invoiceEntry.Text), c.window)
+				return
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error loading invoice: %v", err), c.window)
+				return
+			}
+			c.showRefundDialog(inv)
+		}, c.window)
+}
+
+// showRefundDialog lets the cashier choose how much of each item to return.
+// Refunds are paid back through the invoice's payment method and need the
+// supervisor PIN.
+func (c *CashierWindow) showRefundDialog(inv db.RefundableInvoice) {
+	quantityEntries := make([]*widget.Entry, len(inv.Lines))
+	rows := container.NewVBox()
+	for i, line := range inv.Lines {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder("0")
+		if line.Refundable() <= 0 {
+			entry.Disable()
+		}
+		quantityEntries[i] = entry
+		rows.Add(container.NewGridWithColumns(2,
+			widget.NewLabel(fmt.Sprintf("%s\nSold %s, refundable %s @Rp%.2f",
+				line.Product.Name,
+				line.Product.FormatQuantity(line.QuantitySold),
+				line.Product.FormatQuantity(line.Refundable()),
+				line.UnitPrice)),
+			entry,
+		))
+	}
+
+	reasonEntry := widget.NewEntry()
+	reasonEntry.SetPlaceHolder("Reason for refund")
+	pinEntry := widget.NewPasswordEntry()
+
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("Invoice %s, paid by %s, total Rp%.2f",
+			inv.Number, strings.ToUpper(inv.PaymentMethod), inv.Total)),
+		widget.NewSeparator(),
+		rows,
+		widget.NewSeparator(),
+		widget.NewForm(
+			widget.NewFormItem("Reason", reasonEntry),
+			widget.NewFormItem("Supervisor PIN", pinEntry),
+		),
+	)
+
+	refundDialog := dialog.NewCustomConfirm("Refund", "Refund", "Cancel", container.NewVScroll(content),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			var lines []types.RefundLine
+			for i, line := range inv.Lines {
+				text := strings.TrimSpace(quantityEntries[i].Text)
+				if text == "" {
+					continue
+				}
+				quantity, err := strconv.ParseFloat(text, 64)
+				if err != nil || quantity < 0 {
+					dialog.ShowError(fmt.Errorf("invalid quantity for %s", line.Product.Name), c.window)
+					return
+				}
+				line.Quantity = line.Product.RoundQuantity(quantity)
+				lines = append(lines, line)
+			}
+			if strings.TrimSpace(reasonEntry.Text) == "" {
+				dialog.ShowError(fmt.Errorf("a reason is required"), c.window)
+				return
+			}
+
+			ok, err := db.VerifySupervisorPIN(c.database, pinEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to verify PIN: %v", err), c.window)
+				return
+			}
+			if !ok {
+				dialog.ShowError(fmt.Errorf("incorrect supervisor PIN"), c.window)
+				return
+			}
+
+			refund, err := db.CreateRefund(c.database, inv, lines, strings.TrimSpace(reasonEntry.Text), currentUser)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error processing refund: %v", err), c.window)
+				return
+			}
+
+			message := fmt.Sprintf("Refund %s: return Rp%.2f by %s",
+				refund.Number, refund.Amount, strings.ToUpper(inv.PaymentMethod))
+			if refund.PointsReturned > 0 {
+				message += fmt.Sprintf("\n%d points given back", refund.PointsReturned)
+			}
+			if refund.PointsReversed > 0 {
+				message += fmt.Sprintf("\n%d earned points taken back", refund.PointsReversed)
+			}
+			dialog.ShowInformation("Refund", message, c.window)
+			c.Load()
+		}, c.window)
+	refundDialog.Resize(fyne.NewSize(500, 500))
+	refundDialog.Show()
+}
+
+// rupiahDenominations lists the banknotes and coins in circulation, largest first
+var rupiahDenominations = []float64{100000, 50000, 20000, 10000, 5000, 2000, 1000, 500, 200, 100}
+
+// quickTenderAmounts returns the exact total followed by the amounts a customer
+// is most likely to hand over, rounded up to the next common banknote
+func quickTenderAmounts(total float64) []float64 {
+	amounts := []float64{total}
+	for _, note := range []float64{5000, 10000, 20000, 50000, 100000} {
+		amount := math.Ceil(total/note) * note
+		if amount <= amounts[len(amounts)-1] {
+			continue
+		}
+		amounts = append(amounts, amount)
+	}
+	return amounts
+}
+
+// formatChangeBreakdown suggests how to hand back change using as few notes and
+// coins as possible
+func formatChangeBreakdown(change float64) string {
+	remaining := math.Round(change)
+	text := ""
+	for _, denomination := range rupiahDenominations {
+		count := math.Floor(remaining / denomination)
+		if count == 0 {
+			continue
+		}
+		text += fmt.Sprintf("%3.0f x Rp%.0f\n", count, denomination)
+		remaining -= count * denomination
+	}
+	if remaining > 0 {
+		text += fmt.Sprintf("Rounding: Rp%.0f\n", remaining)
+	}
+	return text
+}
+
+// availableToAdd returns how much more of a product can be added to the cart,
+// allowing for the stock of its recipe components already used by the cart
+func (c *CashierWindow) availableToAdd(product types.Product) float64 {
+	available := math.Inf(1)
+	if product.TrackStock {
+		available = c.stock[product.ID] - c.cartUses(product.ID)
+	}
+	for componentID, perUnit := range c.recipes[product.ID] {
+		available = math.Min(available, (c.stock[componentID]-c.cartUses(componentID))/perUnit)
+	}
+	if math.IsInf(available, 1) {
+		return 0
+	}
+	return available
+}
+
+// cartUses returns the quantity of a product the cart already consumes,
+// directly or as a recipe component
+func (c *CashierWindow) cartUses(productID int) float64 {
+	var quantity float64
+	for _, item := range c.cartItems {
+		if item.Product.ID == productID && item.Product.TrackStock {
+			quantity += item.BaseQuantity()
+		}
+		quantity += item.BaseQuantity() * c.recipes[item.Product.ID][productID]
+	}
+	return quantity
+}
+
+// modifierLookup finds the modifier groups offered for a product. Variants
+// without groups of their own use their parent's groups.
+type modifierLookup struct {
+	groups        map[int]types.ModifierGroup
+	productGroups map[int][]int
+}
+
+func newModifierLookup(groups []types.ModifierGroup, productGroups map[int][]int) modifierLookup {
+	lookup := modifierLookup{groups: map[int]types.ModifierGroup{}, productGroups: productGroups}
+	for _, g := range groups {
+		lookup.groups[g.ID] = g
+	}
+	return lookup
+}
+
+func (m modifierLookup) groupsFor(product types.Product) []types.ModifierGroup {
+	ids := m.productGroups[product.ID]
+	if len(ids) == 0 && product.ParentID != 0 {
+		ids = m.productGroups[product.ParentID]
+	}
+
+	var groups []types.ModifierGroup
+	for _, id := range ids {
+		if g, ok := m.groups[id]; ok && len(g.Options) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// showPackageDialog asks whether a product is sold in its base unit or one
+// of its packaging units
+func (c *CashierWindow) showPackageDialog(product types.Product, packages []types.ProductPackage, onSelect func(*types.ProductPackage)) {
+	var d dialog.Dialog
+	list := container.NewVBox(widget.NewButton(
+		fmt.Sprintf("1 %s - Rp%.2f", product.Unit, product.Price),
+		func() {
+			d.Hide()
+			onSelect(nil)
+		},
+	))
+	for _, pkg := range packages {
+		p := pkg
+		list.Add(widget.NewButton(
+			fmt.Sprintf("%s of %s - Rp%.2f", p.Name, product.FormatQuantity(p.Units), p.Price),
+			func() {
+				d.Hide()
+				onSelect(&p)
+			},
+		))
+	}
+
+	d = dialog.NewCustom(product.Name, "Cancel", list, c.window)
+	d.Show()
+}
+
+func (c *CashierWindow) showVariantDialog(parent types.Product, variants []types.Product, onSelect func(types.Product)) {
+	var d dialog.Dialog
+	list := container.NewVBox()
+	for _, variant := range variants {
+		v := variant
+		list.Add(widget.NewButton(
+			fmt.Sprintf("%s - Rp%.2f (Stock: %s)", v.VariantName, v.Price, v.FormatQuantity(v.Stock)),
+			func() {
+				d.Hide()
+				onSelect(v)
+			},
+		))
+	}
+
+	d = dialog.NewCustom(parent.Name, "Cancel", list, c.window)
+	d.Show()
+}
+
+// showModifierDialog asks for the modifiers of a product, enforcing each
+// group's minimum and maximum selections. Products without modifiers go
+// straight through.
+func (c *CashierWindow) showModifierDialog(product types.Product, groups []types.ModifierGroup, onConfirm func([]types.ModifierOption)) {
+	if len(groups) == 0 {
+		onConfirm(nil)
+		return
+	}
+
+	optionLabel := func(o types.ModifierOption) string {
+		if o.PriceDelta == 0 {
+			return o.Name
+		}
+		return fmt.Sprintf("%s (+Rp%.2f)", o.Name, o.PriceDelta)
+	}
+
+	var items []*widget.FormItem
+	selections := make([]func() []string, len(groups))
+	for i, group := range groups {
+		var labels []string
+		for _, o := range group.Options {
+			labels = append(labels, optionLabel(o))
+		}
+
+		if group.MaxSelect == 1 {
+			radio := widget.NewRadioGroup(labels, nil)
+			selections[i] = func() []string {
+				if radio.Selected == "" {
+					return nil
+				}
+				return []string{radio.Selected}
+			}
+			items = append(items, widget.NewFormItem(group.Name, radio))
+		} else {
+			check := widget.NewCheckGroup(labels, nil)
+			selections[i] = func() []string {
+				return check.Selected
+			}
+			items = append(items, widget.NewFormItem(group.Name, check))
+		}
+	}
+
+	dialog.ShowForm(product.Name, "Add", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			var chosen []types.ModifierOption
+			for i, group := range groups {
+				selected := selections[i]()
+				if len(selected) < group.MinSelect {
+					dialog.ShowError(fmt.Errorf("choose at least %d from %s", group.MinSelect, group.Name), c.window)
+					return
+				}
+				if len(selected) > group.MaxSelect {
+					dialog.ShowError(fmt.Errorf("choose at most %d from %s", group.MaxSelect, group.Name), c.window)
+					return
+				}
+
+				// Keep the options in menu order so identical choices share a cart line
+				for _, o := range group.Options {
+					for _, label := range selected {
+						if label == optionLabel(o) {
+							chosen = append(chosen, o)
+						}
+					}
+				}
+			}
+
+			onConfirm(chosen)
+		}, c.window)
+}
@@ -0,0 +1,311 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// chartPalette colours the series of bar and pie charts
+var chartPalette = []color.NRGBA{
+	{R: 0x29, G: 0x80, B: 0xb9, A: 0xff},
+	{R: 0x27, G: 0xae, B: 0x60, A: 0xff},
+	{R: 0xe6, G: 0x7e, B: 0x22, A: 0xff},
+	{R: 0x8e, G: 0x44, B: 0xad, A: 0xff},
+	{R: 0xc0, G: 0x39, B: 0x2b, A: 0xff},
+	{R: 0x16, G: 0xa0, B: 0x85, A: 0xff},
+	{R: 0xf1, G: 0xc4, B: 0x0f, A: 0xff},
+	{R: 0x7f, G: 0x8c, B: 0x8d, A: 0xff},
+}
+
+// chart is a widget drawn from canvas primitives. The draw function is called
+// with the widget's size each time it is laid out, so charts scale with the
+// window.
+type chart struct {
+	widget.BaseWidget
+	minSize fyne.Size
+	draw    func(size fyne.Size) []fyne.CanvasObject
+}
+
+func newChart(minSize fyne.Size, draw func(size fyne.Size) []fyne.CanvasObject) *chart {
+	c := &chart{minSize: minSize, draw: draw}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *chart) CreateRenderer() fyne.WidgetRenderer {
+	return &chartRenderer{chart: c}
+}
+
+type chartRenderer struct {
+	chart   *chart
+	size    fyne.Size
+	objects []fyne.CanvasObject
+}
+
+func (r *chartRenderer) Layout(size fyne.Size) {
+	r.size = size
+	r.objects = r.chart.draw(size)
+}
+
+func (r *chartRenderer) MinSize() fyne.Size {
+	return r.chart.minSize
+}
+
+func (r *chartRenderer) Refresh() {
+	r.Layout(r.size)
+	canvas.Refresh(r.chart)
+}
+
+func (r *chartRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *chartRenderer) Destroy() {}
+
+// chartText places a small label with its top left corner at pos
+func chartText(text string, pos fyne.Position, align fyne.TextAlign) *canvas.Text {
+	t := canvas.NewText(text, theme.ForegroundColor())
+	t.TextSize = theme.CaptionTextSize()
+	t.Alignment = align
+	size := t.MinSize()
+	switch align {
+	case fyne.TextAlignTrailing:
+		pos.X -= size.Width
+	case fyne.TextAlignCenter:
+		pos.X -= size.Width / 2
+	}
+	t.Move(pos)
+	t.Resize(size)
+	return t
+}
+
+func chartLine(from, to fyne.Position, c color.Color, width float32) *canvas.Line {
+	l := canvas.NewLine(c)
+	l.StrokeWidth = width
+	l.Position1 = from
+	l.Position2 = to
+	return l
+}
+
+// chartEmpty is drawn in place of a chart with nothing to show
+func chartEmpty(size fyne.Size) []fyne.CanvasObject {
+	return []fyne.CanvasObject{
+		chartText("No sales in this period", fyne.NewPos(size.Width/2, size.Height/2), fyne.TextAlignCenter),
+	}
+}
+
+// compactAmount shortens large amounts for axis labels, e.g. 1.5M
+func compactAmount(amount float64) string {
+	switch {
+	case math.Abs(amount) >= 1e9:
+		return fmt.Sprintf("%.1fB", amount/1e9)
+	case math.Abs(amount) >= 1e6:
+		return fmt.Sprintf("%.1fM", amount/1e6)
+	case math.Abs(amount) >= 1e3:
+		return fmt.Sprintf("%.1fK", amount/1e3)
+	}
+	return fmt.Sprintf("%.0f", amount)
+}
+
+// newLineChart plots values in order, labelling the first and last point and
+// marking the highest value on the vertical axis
+func newLineChart(labels []string, values []float64) *chart {
+	return newChart(fyne.NewSize(360, 220), func(size fyne.Size) []fyne.CanvasObject {
+		maxValue := 0.0
+		for _, v := range values {
+			maxValue = math.Max(maxValue, v)
+		}
+		if len(values) == 0 || maxValue == 0 {
+			return chartEmpty(size)
+		}
+
+		const left, bottom, top, right = 48, 20, 8, 8
+		plotW := size.Width - left - right
+		plotH := size.Height - top - bottom
+		axis := theme.DisabledColor()
+		objects := []fyne.CanvasObject{
+			chartLine(fyne.NewPos(left, top), fyne.NewPos(left, top+plotH), axis, 1),
+			chartLine(fyne.NewPos(left, top+plotH), fyne.NewPos(left+plotW, top+plotH), axis, 1),
+			chartText(compactAmount(maxValue), fyne.NewPos(left-4, top-6), fyne.TextAlignTrailing),
+			chartText("0", fyne.NewPos(left-4, top+plotH-8), fyne.TextAlignTrailing),
+		}
+
+		point := func(i int) fyne.Position {
+			x := float32(left)
+			if len(values) > 1 {
+				x += plotW * float32(i) / float32(len(values)-1)
+			} else {
+				x += plotW / 2
+			}
+			return fyne.NewPos(x, top+plotH-plotH*float32(values[i]/maxValue))
+		}
+
+		lineColor := theme.PrimaryColor()
+		for i := range values {
+			p := point(i)
+			if i > 0 {
+				objects = append(objects, chartLine(point(i-1), p, lineColor, 2))
+			}
+			dot := canvas.NewCircle(lineColor)
+			dot.Move(fyne.NewPos(p.X-2.5, p.Y-2.5))
+			dot.Resize(fyne.NewSize(5, 5))
+			objects = append(objects, dot)
+		}
+
+		objects = append(objects, chartText(labels[0], fyne.NewPos(left, top+plotH+2), fyne.TextAlignLeading))
+		if len(labels) > 1 {
+			objects = append(objects,
+				chartText(labels[len(labels)-1], fyne.NewPos(left+plotW, top+plotH+2), fyne.TextAlignTrailing))
+		}
+		return objects
+	})
+}
+
+// newBarChart draws one horizontal bar per value, largest expected first
+func newBarChart(labels []string, values []float64) *chart {
+	return newChart(fyne.NewSize(360, 220), func(size fyne.Size) []fyne.CanvasObject {
+		maxValue := 0.0
+		for _, v := range values {
+			maxValue = math.Max(maxValue, v)
+		}
+		if len(values) == 0 || maxValue == 0 {
+			return chartEmpty(size)
+		}
+
+		const labelW, valueW = 110, 52
+		rowH := size.Height / float32(len(values))
+		barH := rowH * 0.7
+		barW := size.Width - labelW - valueW
+		var objects []fyne.CanvasObject
+		for i, v := range values {
+			y := rowH * float32(i)
+			textY := y + (rowH-theme.CaptionTextSize())/2 - 2
+
+			label := labels[i]
+			if runes := []rune(label); len(runes) > 16 {
+				label = string(runes[:15]) + "…"
+			}
+			objects = append(objects, chartText(label, fyne.NewPos(labelW-6, textY), fyne.TextAlignTrailing))
+
+			bar := canvas.NewRectangle(chartPalette[i%len(chartPalette)])
+			w := barW * float32(v/maxValue)
+			bar.Move(fyne.NewPos(labelW, y+(rowH-barH)/2))
+			bar.Resize(fyne.NewSize(w, barH))
+			objects = append(objects, bar,
+				chartText(compactAmount(v), fyne.NewPos(labelW+w+4, textY), fyne.TextAlignLeading))
+		}
+		return objects
+	})
+}
+
+// newHeatmap shades a grid of cells by value, one row per row label and one
+// column per column label. Columns with an empty label are left unlabelled.
+func newHeatmap(rowLabels, columnLabels []string, values [][]float64) *chart {
+	return newChart(fyne.NewSize(360, 200), func(size fyne.Size) []fyne.CanvasObject {
+		maxValue := 0.0
+		for _, row := range values {
+			for _, v := range row {
+				maxValue = math.Max(maxValue, v)
+			}
+		}
+		if maxValue == 0 {
+			return chartEmpty(size)
+		}
+
+		const left, bottom = 36, 18
+		cellW := (size.Width - left) / float32(len(columnLabels))
+		cellH := (size.Height - bottom) / float32(len(rowLabels))
+		r, g, b, _ := theme.PrimaryColor().RGBA()
+
+		var objects []fyne.CanvasObject
+		for i, row := range values {
+			y := cellH * float32(i)
+			objects = append(objects,
+				chartText(rowLabels[i], fyne.NewPos(left-4, y+(cellH-theme.CaptionTextSize())/2-2), fyne.TextAlignTrailing))
+			for j, v := range row {
+				// Empty cells stay faintly visible so the grid reads as a grid
+				alpha := uint8(12 + 243*v/maxValue)
+				cell := canvas.NewRectangle(color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: alpha})
+				cell.Move(fyne.NewPos(left+cellW*float32(j)+1, y+1))
+				cell.Resize(fyne.NewSize(cellW-2, cellH-2))
+				objects = append(objects, cell)
+			}
+		}
+		for j, label := range columnLabels {
+			if label == "" {
+				continue
+			}
+			objects = append(objects,
+				chartText(label, fyne.NewPos(left+cellW*(float32(j)+0.5), size.Height-bottom+2), fyne.TextAlignCenter))
+		}
+		return objects
+	})
+}
+
+// newPieChart draws each value's share of the total as a slice with a legend
+// alongside
+func newPieChart(labels []string, values []float64) *chart {
+	return newChart(fyne.NewSize(360, 200), func(size fyne.Size) []fyne.CanvasObject {
+		total := 0.0
+		for _, v := range values {
+			total += math.Max(v, 0)
+		}
+		if total == 0 {
+			return chartEmpty(size)
+		}
+
+		diameter := size.Height
+		if diameter > size.Width/2 {
+			diameter = size.Width / 2
+		}
+
+		// Slices end at these fractions of a turn, clockwise from 12 o'clock
+		ends := make([]float64, len(values))
+		sum := 0.0
+		for i, v := range values {
+			sum += math.Max(v, 0)
+			ends[i] = sum / total
+		}
+
+		pie := canvas.NewRasterWithPixels(func(x, y, w, h int) color.Color {
+			dx := float64(x) - float64(w)/2
+			dy := float64(y) - float64(h)/2
+			radius := math.Min(float64(w), float64(h)) / 2
+			if dx*dx+dy*dy > radius*radius {
+				return color.Transparent
+			}
+			turn := math.Atan2(dx, -dy) / (2 * math.Pi)
+			if turn < 0 {
+				turn++
+			}
+			for i, end := range ends {
+				if turn <= end {
+					return chartPalette[i%len(chartPalette)]
+				}
+			}
+			return chartPalette[(len(ends)-1)%len(chartPalette)]
+		})
+		pie.Move(fyne.NewPos(0, (size.Height-diameter)/2))
+		pie.Resize(fyne.NewSize(diameter, diameter))
+
+		objects := []fyne.CanvasObject{pie}
+		const swatch, rowH = 10, 18
+		legendX := diameter + 16
+		legendY := (size.Height - rowH*float32(len(values))) / 2
+		for i, label := range labels {
+			y := legendY + rowH*float32(i)
+			box := canvas.NewRectangle(chartPalette[i%len(chartPalette)])
+			box.Move(fyne.NewPos(legendX, y+3))
+			box.Resize(fyne.NewSize(swatch, swatch))
+			text := fmt.Sprintf("%s %.0f%%", label, math.Max(values[i], 0)/total*100)
+			objects = append(objects, box, chartText(text, fyne.NewPos(legendX+swatch+6, y), fyne.TextAlignLeading))
+		}
+		return objects
+	})
+}
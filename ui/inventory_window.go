@@ -5,20 +5,27 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
 	"github.com/hendrisulistya/cashier-app/db"
 	"github.com/hendrisulistya/cashier-app/types"
 )
 
 type InventoryWindow struct {
-	window   fyne.Window
-	database *sql.DB
-	list     *widget.List
-	products []types.Product
+	window       fyne.Window
+	database     *sql.DB
+	list         *widget.List
+	allProducts  []types.Product
+	products     []types.Product
+	lowStockOnly bool
+	showArchived bool
 }
 
 func NewInventoryWindow(window fyne.Window, database *sql.DB) *InventoryWindow {
@@ -29,11 +36,16 @@ func NewInventoryWindow(window fyne.Window, database *sql.DB) *InventoryWindow {
 }
 
 func (i *InventoryWindow) Load() error {
+	if err := db.ApplyScheduledPrices(i.database); err != nil {
+		return fmt.Errorf("could not apply scheduled prices: %v", err)
+	}
+
 	var err error
-	i.products, err = db.GetProducts(i.database)
+	i.allProducts, err = db.GetAllProducts(i.database)
 	if err != nil {
 		return fmt.Errorf("could not fetch products: %v", err)
 	}
+	i.applyFilter()
 
 	content := i.createInventoryContent()
 	i.window.SetContent(content)
@@ -60,11 +72,17 @@ func (i *InventoryWindow) createInventoryContent() fyne.CanvasObject {
 		func() int { return len(i.products) },
 		func() fyne.CanvasObject {
 			return container.NewHBox(
-				widget.NewLabel(""),                   // Product name
-				widget.NewLabel(""),                   // Price
-				widget.NewLabel(""),                   // Stock
-				widget.NewButton("Edit", func() {}),   // Edit button placeholder
-				widget.NewButton("Delete", func() {}), // Delete button placeholder
+				widget.NewLabel(""),                    // Product name
+				widget.NewLabel(""),                    // Price
+				widget.NewLabel(""),                    // Stock
+				widget.NewLabel(""),                    // Low stock flag
+				widget.NewButton("Edit", func() {}),    // Edit button placeholder
+				widget.NewButton("Adjust", func() {}),  // Adjust stock button placeholder
+				widget.NewButton("History", func() {}), // Movement history button placeholder
+				widget.NewButton("Prices", func() {}),  // Price timeline button placeholder
+				widget.NewButton("Options", func() {}), // Variants and modifiers button placeholder
+				widget.NewButton("Archive", func() {}), // Archive or restore button placeholder
+				widget.NewButton("Delete", func() {}),  // Delete button placeholder
 			)
 		},
 		func(id widget.ListItemID, item fyne.CanvasObject) {
@@ -72,19 +90,73 @@ func (i *InventoryWindow) createInventoryContent() fyne.CanvasObject {
 			box := item.(*fyne.Container)
 
 			// Update labels
-			box.Objects[0].(*widget.Label).SetText(product.Name)
-			box.Objects[1].(*widget.Label).SetText(fmt.Sprintf("Rp%.2f", product.Price))
-			box.Objects[2].(*widget.Label).SetText(fmt.Sprintf("%d", product.Stock))
+			if product.SKU != "" {
+				box.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s [%s]", product.Name, product.SKU))
+			} else {
+				box.Objects[0].(*widget.Label).SetText(product.Name)
+			}
+			box.Objects[1].(*widget.Label).SetText(fmt.Sprintf("Rp%.2f (%.1f%% margin)", product.Price, product.Margin()))
+			box.Objects[2].(*widget.Label).SetText(product.FormatQuantity(product.Stock))
+
+			flag := box.Objects[3].(*widget.Label)
+			if product.IsLowStock() {
+				flag.SetText(fmt.Sprintf("LOW (reorder at %s)", types.FormatQuantity(product.ReorderPoint, product.Precision)))
+				flag.Importance = widget.DangerImportance
+			} else {
+				flag.SetText("")
+				flag.Importance = widget.MediumImportance
+			}
+			flag.Refresh()
 
 			// Update edit button
-			box.Objects[3].(*widget.Button).OnTapped = func() {
+			box.Objects[4].(*widget.Button).OnTapped = func() {
 				i.showEditDialog(product)
 			}
 
-			// Update delete button
-			box.Objects[4].(*widget.Button).OnTapped = func() {
+			// Update adjust stock button
+			box.Objects[5].(*widget.Button).OnTapped = func() {
+				i.showAdjustStockDialog(product)
+			}
+
+			// Update history button
+			box.Objects[6].(*widget.Button).OnTapped = func() {
+				i.showMovementHistory(product)
+			}
+
+			// Update prices button
+			box.Objects[7].(*widget.Button).OnTapped = func() {
+				i.showPriceTimeline(product)
+			}
+
+			// Update options button
+			box.Objects[8].(*widget.Button).OnTapped = func() {
+				i.showProductOptions(product)
+			}
+
+			// Update archive button, which restores archived products
+			archiveButton := box.Objects[9].(*widget.Button)
+			if product.Active {
+				archiveButton.SetText("Archive")
+				archiveButton.OnTapped = func() {
+					i.showArchiveDialog(product)
+				}
+			} else {
+				archiveButton.SetText("Restore")
+				archiveButton.OnTapped = func() {
+					i.restoreProduct(product)
+				}
+			}
+
+			// Update delete button, offered only once a product is archived
+			deleteButton := box.Objects[10].(*widget.Button)
+			deleteButton.OnTapped = func() {
 				i.showDeleteDialog(product)
 			}
+			if product.Active {
+				deleteButton.Hide()
+			} else {
+				deleteButton.Show()
+			}
 		},
 	)
 
@@ -94,9 +166,54 @@ func (i *InventoryWindow) createInventoryContent() fyne.CanvasObject {
 	})
 	addButton.Importance = widget.HighImportance
 
+	// Low stock filter and reorder list
+	lowStockCheck := widget.NewCheck("Show low stock only", func(checked bool) {
+		i.lowStockOnly = checked
+		i.applyFilter()
+		i.list.Refresh()
+	})
+	lowStockCheck.SetChecked(i.lowStockOnly)
+
+	archivedCheck := widget.NewCheck("Show archived", func(checked bool) {
+		i.showArchived = checked
+		i.applyFilter()
+		i.list.Refresh()
+	})
+	archivedCheck.SetChecked(i.showArchived)
+
+	reorderButton := widget.NewButton("Draft Reorder List", func() {
+		i.showReorderList()
+	})
+
+	importButton := widget.NewButton("Import CSV", func() {
+		i.showImportDialog()
+	})
+	exportButton := widget.NewButton("Export CSV", func() {
+		i.showExportDialog()
+	})
+
+	stocktakeButton := widget.NewButton("Stocktake", func() {
+		stocktakeWindow := NewStocktakeWindow(i.window, i.database)
+		if err := stocktakeWindow.Load(); err != nil {
+			log.Printf("Error loading stocktake window: %v", err)
+			dialog.ShowError(err, i.window)
+		}
+	})
+
+	expiringButton := widget.NewButton("Expiring Soon", func() {
+		i.showExpiringLots()
+	})
+
+	modifiersButton := widget.NewButton("Modifiers", func() {
+		i.showModifierGroups()
+	})
+
+	toolbar := container.NewHBox(lowStockCheck, archivedCheck, reorderButton, stocktakeButton, expiringButton, modifiersButton,
+		layout.NewSpacer(), importButton, exportButton)
+
 	// Layout setup
 	content := container.NewBorder(
-		header,
+		container.NewVBox(header, toolbar),
 		addButton,
 		nil,
 		nil,
@@ -107,14 +224,33 @@ func (i *InventoryWindow) createInventoryContent() fyne.CanvasObject {
 }
 
 func (i *InventoryWindow) showAddDialog() {
+	skuEntry := widget.NewEntry()
 	nameEntry := widget.NewEntry()
 	priceEntry := widget.NewEntry()
+	costEntry := widget.NewEntry()
 	stockEntry := widget.NewEntry()
+	unitEntry := widget.NewEntry()
+	unitEntry.SetText("pcs")
+	precisionEntry := widget.NewEntry()
+	precisionEntry.SetText("0")
+	reorderPointEntry := widget.NewEntry()
+	reorderPointEntry.SetPlaceHolder("0 disables the alert")
+	reorderQuantityEntry := widget.NewEntry()
+	categoryEntry := widget.NewEntry()
+	shelfEntry := widget.NewEntry()
 
 	items := []*widget.FormItem{
+		widget.NewFormItem("SKU", skuEntry),
 		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Category", categoryEntry),
+		widget.NewFormItem("Shelf", shelfEntry),
 		widget.NewFormItem("Price", priceEntry),
+		widget.NewFormItem("Cost", costEntry),
 		widget.NewFormItem("Stock", stockEntry),
+		widget.NewFormItem("Unit", unitEntry),
+		widget.NewFormItem("Decimals", precisionEntry),
+		widget.NewFormItem("Reorder Point", reorderPointEntry),
+		widget.NewFormItem("Reorder Qty", reorderQuantityEntry),
 	}
 
 	dialog.ShowForm("Add New Product", "Add", "Cancel", items,
@@ -129,19 +265,45 @@ func (i *InventoryWindow) showAddDialog() {
 				return
 			}
 
-			stock, err := strconv.Atoi(stockEntry.Text)
+			cost, err := parseCost(costEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, i.window)
+				return
+			}
+
+			stock, err := strconv.ParseFloat(stockEntry.Text, 64)
 			if err != nil {
 				dialog.ShowError(fmt.Errorf("invalid stock format"), i.window)
 				return
 			}
 
+			precision, err := strconv.Atoi(precisionEntry.Text)
+			if err != nil || precision < 0 || precision > 3 {
+				dialog.ShowError(fmt.Errorf("decimals must be between 0 and 3"), i.window)
+				return
+			}
+
+			reorderPoint, reorderQuantity, err := parseReorderSettings(reorderPointEntry.Text, reorderQuantityEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, i.window)
+				return
+			}
+
 			product := types.Product{
-				Name:  nameEntry.Text,
-				Price: price,
-				Stock: stock,
+				SKU:             skuEntry.Text,
+				Name:            nameEntry.Text,
+				Category:        categoryEntry.Text,
+				Shelf:           shelfEntry.Text,
+				Price:           price,
+				Cost:            cost,
+				Unit:            unitEntry.Text,
+				Precision:       precision,
+				ReorderPoint:    reorderPoint,
+				ReorderQuantity: reorderQuantity,
 			}
+			product.Stock = product.RoundQuantity(stock)
 
-			if err := db.AddProduct(i.database, product); err != nil {
+			if err := db.AddProduct(i.database, product, currentUser); err != nil {
 				dialog.ShowError(fmt.Errorf("failed to add product: %v", err), i.window)
 				return
 			}
@@ -152,19 +314,48 @@ func (i *InventoryWindow) showAddDialog() {
 }
 
 func (i *InventoryWindow) showEditDialog(product types.Product) {
+	skuEntry := widget.NewEntry()
+	skuEntry.SetText(product.SKU)
+
 	nameEntry := widget.NewEntry()
 	nameEntry.SetText(product.Name)
 
 	priceEntry := widget.NewEntry()
 	priceEntry.SetText(fmt.Sprintf("%.2f", product.Price))
 
-	stockEntry := widget.NewEntry()
-	stockEntry.SetText(fmt.Sprintf("%d", product.Stock))
+	costEntry := widget.NewEntry()
+	costEntry.SetText(fmt.Sprintf("%.2f", product.Cost))
+
+	unitEntry := widget.NewEntry()
+	unitEntry.SetText(product.Unit)
+
+	precisionEntry := widget.NewEntry()
+	precisionEntry.SetText(strconv.Itoa(product.Precision))
+
+	reorderPointEntry := widget.NewEntry()
+	reorderPointEntry.SetText(types.FormatQuantity(product.ReorderPoint, product.Precision))
+	reorderPointEntry.SetPlaceHolder("0 disables the alert")
+
+	reorderQuantityEntry := widget.NewEntry()
+	reorderQuantityEntry.SetText(types.FormatQuantity(product.ReorderQuantity, product.Precision))
+
+	categoryEntry := widget.NewEntry()
+	categoryEntry.SetText(product.Category)
+
+	shelfEntry := widget.NewEntry()
+	shelfEntry.SetText(product.Shelf)
 
 	items := []*widget.FormItem{
+		widget.NewFormItem("SKU", skuEntry),
 		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Category", categoryEntry),
+		widget.NewFormItem("Shelf", shelfEntry),
 		widget.NewFormItem("Price", priceEntry),
-		widget.NewFormItem("Stock", stockEntry),
+		widget.NewFormItem("Cost", costEntry),
+		widget.NewFormItem("Unit", unitEntry),
+		widget.NewFormItem("Decimals", precisionEntry),
+		widget.NewFormItem("Reorder Point", reorderPointEntry),
+		widget.NewFormItem("Reorder Qty", reorderQuantityEntry),
 	}
 
 	dialog.ShowForm("Edit Product", "Save", "Cancel", items,
@@ -179,20 +370,39 @@ func (i *InventoryWindow) showEditDialog(product types.Product) {
 				return
 			}
 
-			stock, err := strconv.Atoi(stockEntry.Text)
+			cost, err := parseCost(costEntry.Text)
 			if err != nil {
-				dialog.ShowError(fmt.Errorf("invalid stock format"), i.window)
+				dialog.ShowError(err, i.window)
+				return
+			}
+
+			precision, err := strconv.Atoi(precisionEntry.Text)
+			if err != nil || precision < 0 || precision > 3 {
+				dialog.ShowError(fmt.Errorf("decimals must be between 0 and 3"), i.window)
+				return
+			}
+
+			reorderPoint, reorderQuantity, err := parseReorderSettings(reorderPointEntry.Text, reorderQuantityEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, i.window)
 				return
 			}
 
 			updatedProduct := types.Product{
-				ID:    product.ID,
-				Name:  nameEntry.Text,
-				Price: price,
-				Stock: stock,
+				ID:              product.ID,
+				SKU:             skuEntry.Text,
+				Name:            nameEntry.Text,
+				Category:        categoryEntry.Text,
+				Shelf:           shelfEntry.Text,
+				Price:           price,
+				Cost:            cost,
+				Unit:            unitEntry.Text,
+				Precision:       precision,
+				ReorderPoint:    reorderPoint,
+				ReorderQuantity: reorderQuantity,
 			}
 
-			if err := db.UpdateProduct(i.database, updatedProduct); err != nil {
+			if err := db.UpdateProduct(i.database, updatedProduct, currentUser); err != nil {
 				dialog.ShowError(fmt.Errorf("failed to update product: %v", err), i.window)
 				return
 			}
@@ -202,9 +412,218 @@ func (i *InventoryWindow) showEditDialog(product types.Product) {
 		}, i.window)
 }
 
+func (i *InventoryWindow) showAdjustStockDialog(product types.Product) {
+	typeSelect := widget.NewSelect([]string{types.MovementAdjustment, types.MovementCount}, nil)
+	typeSelect.SetSelected(types.MovementAdjustment)
+
+	quantityEntry := widget.NewEntry()
+	quantityEntry.SetPlaceHolder("Change, e.g. -2 or 10")
+	typeSelect.OnChanged = func(value string) {
+		if value == types.MovementCount {
+			quantityEntry.SetPlaceHolder("Quantity counted on hand")
+		} else {
+			quantityEntry.SetPlaceHolder("Change, e.g. -2 or 10")
+		}
+	}
+
+	noteEntry := widget.NewEntry()
+	noteEntry.SetPlaceHolder("Reason for the change")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Current", widget.NewLabel(product.FormatQuantity(product.Stock))),
+		widget.NewFormItem("Type", typeSelect),
+		widget.NewFormItem(fmt.Sprintf("Quantity (%s)", product.Unit), quantityEntry),
+		widget.NewFormItem("Note", noteEntry),
+	}
+
+	dialog.ShowForm(fmt.Sprintf("Adjust Stock - %s", product.Name), "Save", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			quantity, err := strconv.ParseFloat(quantityEntry.Text, 64)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid quantity format"), i.window)
+				return
+			}
+
+			movement := types.StockMovement{
+				ProductID: product.ID,
+				Type:      typeSelect.Selected,
+				Quantity:  product.RoundQuantity(quantity),
+				Username:  currentUser,
+				Note:      noteEntry.Text,
+			}
+
+			if err := db.AdjustStock(i.database, movement); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to adjust stock: %v", err), i.window)
+				return
+			}
+
+			// Refresh the product list
+			i.refreshProducts()
+		}, i.window)
+}
+
+func (i *InventoryWindow) showMovementHistory(product types.Product) {
+	movements, err := db.GetStockMovements(i.database, product.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load stock history: %v", err), i.window)
+		return
+	}
+
+	history := fmt.Sprintf("%-16s | %-10s | %10s | %10s | %-10s | %s\n",
+		"Date", "Type", "Change", "Balance", "User", "Reference")
+	history += "------------------------------------------------------------------------------\n"
+	for _, m := range movements {
+		reference := m.Reference
+		if m.Note != "" {
+			reference = strings.TrimSpace(reference + " " + m.Note)
+		}
+		history += fmt.Sprintf("%-16s | %-10s | %10s | %10s | %-10s | %s\n",
+			m.CreatedAt.Format("2006-01-02 15:04"),
+			m.Type,
+			types.FormatQuantity(m.Quantity, product.Precision),
+			types.FormatQuantity(m.StockAfter, product.Precision),
+			m.Username,
+			reference)
+	}
+
+	historyText := widget.NewTextGridFromString(history)
+	scroll := container.NewScroll(historyText)
+	scroll.SetMinSize(fyne.NewSize(700, 400))
+
+	dialog.ShowCustom(fmt.Sprintf("Stock History - %s", product.Name), "Close", scroll, i.window)
+}
+
+// showPriceTimeline shows a product's past, current and scheduled prices and
+// lets a manager schedule a new one
+func (i *InventoryWindow) showPriceTimeline(product types.Product) {
+	changes, err := db.GetPriceHistory(i.database, product.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load price history: %v", err), i.window)
+		return
+	}
+
+	var d dialog.Dialog
+	timeline := container.NewVBox()
+	now := time.Now()
+	current := false
+	for _, change := range changes {
+		c := change
+		status := ""
+		switch {
+		case c.EffectiveFrom.After(now):
+			status = "scheduled"
+		case !current:
+			status = "current"
+			current = true
+		}
+
+		text := fmt.Sprintf("%-16s | Rp%-12.2f | %-10s | %s",
+			c.EffectiveFrom.Format("2006-01-02 15:04"), c.Price, c.CreatedBy, status)
+		row := container.NewHBox(widget.NewLabelWithStyle(text, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true}))
+		if status == "scheduled" {
+			row.Add(widget.NewButton("Cancel", func() {
+				if err := db.CancelPriceChange(i.database, c.ID); err != nil {
+					dialog.ShowError(fmt.Errorf("failed to cancel price change: %v", err), i.window)
+					return
+				}
+				d.Hide()
+				i.showPriceTimeline(product)
+			}))
+		}
+		timeline.Add(row)
+	}
+
+	scheduleButton := widget.NewButton("Schedule Price Change", func() {
+		d.Hide()
+		i.showSchedulePriceDialog(product)
+	})
+	scheduleButton.Importance = widget.HighImportance
+
+	header := widget.NewLabelWithStyle("Effective From   | Price          | By         | Status",
+		fyne.TextAlignLeading, fyne.TextStyle{Monospace: true, Bold: true})
+	scroll := container.NewVScroll(timeline)
+	scroll.SetMinSize(fyne.NewSize(600, 300))
+
+	d = dialog.NewCustom(fmt.Sprintf("Price Timeline - %s", product.Name), "Close",
+		container.NewBorder(header, scheduleButton, nil, nil, scroll), i.window)
+	d.Show()
+}
+
+func (i *InventoryWindow) showSchedulePriceDialog(product types.Product) {
+	priceEntry := widget.NewEntry()
+	priceEntry.SetText(fmt.Sprintf("%.2f", product.Price))
+	dateEntry := widget.NewEntry()
+	dateEntry.SetText(time.Now().AddDate(0, 1, 1-time.Now().Day()).Format("2006-01-02"))
+	dateEntry.SetPlaceHolder("YYYY-MM-DD or YYYY-MM-DD HH:MM")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("New Price", priceEntry),
+		widget.NewFormItem("Effective From", dateEntry),
+	}
+
+	dialog.ShowForm("Schedule Price Change", "Schedule", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			price, err := strconv.ParseFloat(priceEntry.Text, 64)
+			if err != nil || price < 0 {
+				dialog.ShowError(fmt.Errorf("invalid price format"), i.window)
+				return
+			}
+
+			effectiveFrom, err := time.ParseInLocation("2006-01-02 15:04", strings.TrimSpace(dateEntry.Text), time.Local)
+			if err != nil {
+				effectiveFrom, err = time.ParseInLocation("2006-01-02", strings.TrimSpace(dateEntry.Text), time.Local)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid date format, use YYYY-MM-DD"), i.window)
+				return
+			}
+
+			if err := db.SchedulePriceChange(i.database, product.ID, price, effectiveFrom, currentUser); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to schedule price change: %v", err), i.window)
+				return
+			}
+
+			i.refreshProducts()
+			i.showPriceTimeline(product)
+		}, i.window)
+}
+
+func (i *InventoryWindow) showArchiveDialog(product types.Product) {
+	dialog.ShowConfirm("Archive Product",
+		fmt.Sprintf("Archive %s? It will be hidden from the cashier but kept in reports and invoices.", product.Name),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			if err := db.ArchiveProduct(i.database, product.ID); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to archive product: %v", err), i.window)
+				return
+			}
+
+			i.refreshProducts()
+		}, i.window)
+}
+
+func (i *InventoryWindow) restoreProduct(product types.Product) {
+	if err := db.RestoreProduct(i.database, product.ID); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to restore product: %v", err), i.window)
+		return
+	}
+	i.refreshProducts()
+}
+
 func (i *InventoryWindow) showDeleteDialog(product types.Product) {
 	dialog.ShowConfirm("Delete Product",
-		fmt.Sprintf("Are you sure you want to delete %s?", product.Name),
+		fmt.Sprintf("Permanently delete %s? Only products that have never been sold can be deleted.", product.Name),
 		func(confirm bool) {
 			if !confirm {
 				return
@@ -220,12 +639,757 @@ func (i *InventoryWindow) showDeleteDialog(product types.Product) {
 		}, i.window)
 }
 
+// showReorderList drafts a reorder list from every low-stock product and
+// offers to turn it into a purchase order
+func (i *InventoryWindow) showReorderList() {
+	var lowStock []types.Product
+	for _, product := range i.allProducts {
+		if product.Active && product.IsLowStock() {
+			lowStock = append(lowStock, product)
+		}
+	}
+
+	if len(lowStock) == 0 {
+		dialog.ShowInformation("Reorder List", "No products are at or below their reorder point", i.window)
+		return
+	}
+
+	list := fmt.Sprintf("Reorder List - %s\n\n", time.Now().Format("2006-01-02 15:04"))
+	list += fmt.Sprintf("%-20s | %12s | %12s | %12s\n", "Product", "On Hand", "Reorder At", "Order Qty")
+	list += "----------------------------------------------------------------\n"
+	for _, product := range lowStock {
+		list += fmt.Sprintf("%-20s | %12s | %12s | %12s\n",
+			product.Name,
+			product.FormatQuantity(product.Stock),
+			product.FormatQuantity(product.ReorderPoint),
+			product.FormatQuantity(product.SuggestedReorder()))
+	}
+
+	suppliers, err := db.GetSuppliers(i.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("could not fetch suppliers: %v", err), i.window)
+		return
+	}
+	supplierNames := make([]string, len(suppliers))
+	for idx, supplier := range suppliers {
+		supplierNames[idx] = supplier.Name
+	}
+	supplierSelect := widget.NewSelect(supplierNames, nil)
+	supplierSelect.PlaceHolder = "Select supplier"
+
+	createOrderButton := widget.NewButton("Create Purchase Order", func() {
+		if supplierSelect.SelectedIndex() < 0 {
+			dialog.ShowError(fmt.Errorf("please select a supplier"), i.window)
+			return
+		}
+
+		order := types.PurchaseOrder{
+			SupplierID: suppliers[supplierSelect.SelectedIndex()].ID,
+			Note:       "Drafted from reorder list",
+		}
+		for _, product := range lowStock {
+			if product.SuggestedReorder() <= 0 {
+				continue
+			}
+			order.Lines = append(order.Lines, types.PurchaseOrderLine{
+				Product:         product,
+				QuantityOrdered: product.SuggestedReorder(),
+			})
+		}
+
+		number, err := db.CreatePurchaseOrder(i.database, order, currentUser)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to create purchase order: %v", err), i.window)
+			return
+		}
+		dialog.ShowInformation("Success", fmt.Sprintf("Purchase order %s created", number), i.window)
+	})
+
+	listScroll := container.NewScroll(widget.NewTextGridFromString(list))
+	listScroll.SetMinSize(fyne.NewSize(650, 300))
+
+	content := container.NewBorder(
+		nil,
+		container.NewHBox(supplierSelect, createOrderButton),
+		nil,
+		nil,
+		listScroll,
+	)
+
+	dialog.ShowCustom("Reorder List", "Close", content, i.window)
+}
+
+func (i *InventoryWindow) showImportDialog() {
+	openDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, i.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		plan, err := db.PlanProductImport(i.database, reader)
+		if err != nil {
+			dialog.ShowError(err, i.window)
+			return
+		}
+		i.showImportPreview(plan)
+	}, i.window)
+	openDialog.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
+	openDialog.Show()
+}
+
+// showImportPreview shows the dry run of an import and applies it on confirm
+func (i *InventoryWindow) showImportPreview(plan db.ProductImportPlan) {
+	preview := fmt.Sprintf("%d to create, %d to update, %d with errors\n\n", plan.Creates, plan.Updates, plan.Errors)
+	for _, row := range plan.Rows {
+		switch row.Action {
+		case db.ImportError:
+			preview += fmt.Sprintf("Line %4d  ERROR   %s: %s\n", row.Line, row.Product.Name, strings.Join(row.Errors, "; "))
+		default:
+			stock := "unchanged"
+			if row.HasStock {
+				stock = row.Product.FormatQuantity(row.Product.Stock)
+			}
+			preview += fmt.Sprintf("Line %4d  %-7s %-20s Rp%.2f  stock %s\n",
+				row.Line, strings.ToUpper(row.Action), row.Product.Name, row.Product.Price, stock)
+		}
+	}
+
+	scroll := container.NewScroll(widget.NewTextGridFromString(preview))
+	scroll.SetMinSize(fyne.NewSize(700, 400))
+
+	if plan.Errors > 0 {
+		dialog.ShowCustom("Import Preview - fix the errors and import again", "Close", scroll, i.window)
+		return
+	}
+
+	dialog.ShowCustomConfirm("Import Preview", "Apply", "Cancel", scroll,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			if err := db.ApplyProductImport(i.database, plan, currentUser); err != nil {
+				dialog.ShowError(fmt.Errorf("import failed, no changes were made: %v", err), i.window)
+				return
+			}
+
+			dialog.ShowInformation("Import Complete",
+				fmt.Sprintf("%d products created, %d updated", plan.Creates, plan.Updates), i.window)
+			i.refreshProducts()
+		}, i.window)
+}
+
+func (i *InventoryWindow) showExportDialog() {
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, i.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if err := db.ExportProducts(i.database, writer); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to export products: %v", err), i.window)
+			return
+		}
+		dialog.ShowInformation("Export Complete", fmt.Sprintf("Products exported to %s", writer.URI().Path()), i.window)
+	}, i.window)
+	saveDialog.SetFileName(fmt.Sprintf("products_%s.csv", time.Now().Format("20060102")))
+	saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
+	saveDialog.Show()
+}
+
+// showExpiringLots lists lots that have expired or expire within the
+// lookahead period, soonest first
+func (i *InventoryWindow) showExpiringLots() {
+	settings, err := db.GetSettings(i.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("error loading settings: %v", err), i.window)
+		return
+	}
+
+	daysEntry := widget.NewEntry()
+	daysEntry.SetText(strconv.Itoa(settings.ExpiryLookaheadDays))
+	report := widget.NewTextGrid()
+
+	refresh := func() {
+		days, err := strconv.Atoi(daysEntry.Text)
+		if err != nil || days < 0 {
+			dialog.ShowError(fmt.Errorf("invalid number of days"), i.window)
+			return
+		}
+
+		lots, err := db.GetExpiringLots(i.database, days)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to load lots: %v", err), i.window)
+			return
+		}
+
+		text := fmt.Sprintf("%-10s | %-20s | %-10s | %12s | %12s | %s\n",
+			"Expiry", "Product", "Lot", "Remaining", "Cost Value", "Status")
+		text += strings.Repeat("-", 90) + "\n"
+		today := time.Now()
+		var totalValue float64
+		for _, l := range lots {
+			status := fmt.Sprintf("%d days left", int(l.ExpiryDate.Sub(today.Truncate(24*time.Hour)).Hours()/24))
+			if l.IsExpired(today) {
+				status = "EXPIRED"
+			}
+			value := l.QuantityRemaining * l.Product.Cost
+			totalValue += value
+			text += fmt.Sprintf("%-10s | %-20s | %-10s | %12s | Rp%10.2f | %s\n",
+				l.ExpiryDate.Format("2006-01-02"), l.Product.Name, l.LotNumber,
+				l.Product.FormatQuantity(l.QuantityRemaining), value, status)
+		}
+		if len(lots) == 0 {
+			text += "No lots expire in this period\n"
+		}
+		text += fmt.Sprintf("\nStock at risk: Rp%.2f\n", totalValue)
+		report.SetText(text)
+	}
+	refresh()
+
+	toolbar := container.NewHBox(widget.NewLabel("Look ahead (days)"), daysEntry, widget.NewButton("Refresh", refresh))
+	scroll := container.NewScroll(report)
+	scroll.SetMinSize(fyne.NewSize(800, 400))
+
+	dialog.ShowCustom("Expiring Soon", "Close", container.NewBorder(toolbar, nil, nil, nil, scroll), i.window)
+}
+
+// showProductOptions lists a product's variants and the modifier groups
+// offered when it is sold
+func (i *InventoryWindow) showProductOptions(product types.Product) {
+	groups, err := db.GetModifierGroups(i.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load modifiers: %v", err), i.window)
+		return
+	}
+	productGroups, err := db.GetProductModifierGroupIDs(i.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load modifiers: %v", err), i.window)
+		return
+	}
+
+	var groupNames []string
+	groupIDs := map[string]int{}
+	var selected []string
+	for _, g := range groups {
+		groupNames = append(groupNames, g.Name)
+		groupIDs[g.Name] = g.ID
+		for _, id := range productGroups[product.ID] {
+			if id == g.ID {
+				selected = append(selected, g.Name)
+			}
+		}
+	}
+	groupCheck := widget.NewCheckGroup(groupNames, nil)
+	groupCheck.SetSelected(selected)
+
+	content := container.NewVBox()
+	var d dialog.Dialog
+
+	if product.ParentID == 0 {
+		variantText := ""
+		for _, p := range i.allProducts {
+			if p.ParentID == product.ID {
+				variantText += fmt.Sprintf("%-20s Rp%-12.2f Stock: %s\n", p.VariantName, p.Price, p.FormatQuantity(p.Stock))
+			}
+		}
+		if variantText == "" {
+			variantText = "No variants\n"
+		}
+
+		addVariantButton := widget.NewButton("Add Variant", func() {
+			d.Hide()
+			i.showAddVariantDialog(product)
+		})
+
+		content.Add(widget.NewLabelWithStyle("Variants", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		content.Add(widget.NewTextGridFromString(variantText))
+		content.Add(addVariantButton)
+		content.Add(widget.NewSeparator())
+	}
+
+	packagesButton := widget.NewButton("Packaging Units", func() {
+		d.Hide()
+		i.showPackagesDialog(product)
+	})
+	content.Add(widget.NewLabelWithStyle("Units", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(packagesButton)
+	content.Add(widget.NewSeparator())
+
+	recipeButton := widget.NewButton("Recipe", func() {
+		d.Hide()
+		i.showRecipeDialog(product)
+	})
+	content.Add(widget.NewLabelWithStyle("Recipe", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(recipeButton)
+	content.Add(widget.NewSeparator())
+
+	content.Add(widget.NewLabelWithStyle("Modifier Groups", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	if product.ParentID != 0 {
+		content.Add(widget.NewLabel("Leave empty to use the groups of the parent product"))
+	}
+	content.Add(groupCheck)
+
+	saveButton := widget.NewButton("Save Modifiers", func() {
+		var ids []int
+		for _, name := range groupCheck.Selected {
+			ids = append(ids, groupIDs[name])
+		}
+		if err := db.SetProductModifierGroups(i.database, product.ID, ids); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save modifiers: %v", err), i.window)
+			return
+		}
+		d.Hide()
+	})
+	saveButton.Importance = widget.HighImportance
+	content.Add(saveButton)
+
+	d = dialog.NewCustom(product.Name, "Close", content, i.window)
+	d.Resize(fyne.NewSize(500, 450))
+	d.Show()
+}
+
+// showPackagesDialog lists a product's packaging units, such as a carton of
+// 24, each with its own barcode and price
+func (i *InventoryWindow) showPackagesDialog(product types.Product) {
+	packages, err := db.GetPackages(i.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load packaging units: %v", err), i.window)
+		return
+	}
+
+	var d dialog.Dialog
+	list := container.NewVBox(widget.NewLabel(fmt.Sprintf("Base unit: %s at Rp%.2f", product.Unit, product.Price)))
+	for _, pkg := range packages[product.ID] {
+		p := pkg
+		text := fmt.Sprintf("%-10s %s  Rp%.2f  %s", p.Name, product.FormatQuantity(p.Units), p.Price, p.Barcode)
+		deleteButton := widget.NewButton("Delete", func() {
+			if err := db.DeletePackage(i.database, p.ID); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to delete packaging unit: %v", err), i.window)
+				return
+			}
+			d.Hide()
+			i.showPackagesDialog(product)
+		})
+		list.Add(container.NewBorder(nil, nil, nil, deleteButton, widget.NewLabel(text)))
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. Carton")
+	unitsEntry := widget.NewEntry()
+	unitsEntry.SetPlaceHolder(fmt.Sprintf("%s per package", product.Unit))
+	priceEntry := widget.NewEntry()
+	priceEntry.SetPlaceHolder("Package price")
+	barcodeEntry := widget.NewEntry()
+	barcodeEntry.SetPlaceHolder("Barcode")
+
+	addButton := widget.NewButton("Add Packaging Unit", func() {
+		units, err := strconv.ParseFloat(unitsEntry.Text, 64)
+		if err != nil || units <= 0 {
+			dialog.ShowError(fmt.Errorf("invalid units per package"), i.window)
+			return
+		}
+		price, err := strconv.ParseFloat(priceEntry.Text, 64)
+		if err != nil || price < 0 {
+			dialog.ShowError(fmt.Errorf("invalid price format"), i.window)
+			return
+		}
+
+		pkg := types.ProductPackage{
+			ProductID: product.ID,
+			Name:      strings.TrimSpace(nameEntry.Text),
+			Barcode:   strings.TrimSpace(barcodeEntry.Text),
+			Units:     product.RoundQuantity(units),
+			Price:     price,
+		}
+		if err := db.AddPackage(i.database, pkg); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to add packaging unit: %v", err), i.window)
+			return
+		}
+		d.Hide()
+		i.showPackagesDialog(product)
+	})
+	addButton.Importance = widget.HighImportance
+
+	content := container.NewVBox(
+		list,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(4, nameEntry, unitsEntry, priceEntry, barcodeEntry),
+		addButton,
+	)
+
+	d = dialog.NewCustom(fmt.Sprintf("Packaging Units - %s", product.Name), "Close", content, i.window)
+	d.Resize(fyne.NewSize(600, 350))
+	d.Show()
+}
+
+// showRecipeDialog edits the components a product is made from and shows its
+// theoretical cost against the selling price
+func (i *InventoryWindow) showRecipeDialog(product types.Product) {
+	recipe, err := db.GetRecipe(i.database, product.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load recipe: %v", err), i.window)
+		return
+	}
+
+	var components []types.Product
+	var componentNames []string
+	for _, p := range i.allProducts {
+		if p.ID != product.ID && p.Active && p.TrackStock {
+			components = append(components, p)
+			componentNames = append(componentNames, p.Name)
+		}
+	}
+
+	// Each row is a component and the quantity used per unit
+	type componentRow struct {
+		product  *widget.Select
+		quantity *widget.Entry
+	}
+	var rows []componentRow
+	rowsBox := container.NewVBox()
+	costLabel := widget.NewLabel("")
+
+	// readRows parses the rows, skipping any without a product
+	readRows := func() ([]types.RecipeComponent, error) {
+		var result []types.RecipeComponent
+		for _, row := range rows {
+			if row.product.SelectedIndex() < 0 {
+				continue
+			}
+			component := components[row.product.SelectedIndex()]
+			quantity, err := strconv.ParseFloat(row.quantity.Text, 64)
+			if err != nil || quantity <= 0 {
+				return nil, fmt.Errorf("invalid quantity for %s", component.Name)
+			}
+			result = append(result, types.RecipeComponent{Component: component, Quantity: quantity})
+		}
+		return result, nil
+	}
+
+	trackCheck := widget.NewCheck("Also deduct this product's own stock", nil)
+	trackCheck.SetChecked(product.TrackStock)
+
+	updateCost := func() {
+		parsed, err := readRows()
+		if err != nil {
+			costLabel.SetText(err.Error())
+			return
+		}
+		cost := types.RecipeCost(parsed)
+		if trackCheck.Checked && len(parsed) > 0 {
+			cost += product.Cost
+		}
+		margin := 0.0
+		if product.Price > 0 {
+			margin = (product.Price - cost) / product.Price * 100
+		}
+		costLabel.SetText(fmt.Sprintf("Theoretical cost: Rp%.2f   Price: Rp%.2f   Margin: %.1f%%", cost, product.Price, margin))
+	}
+	trackCheck.OnChanged = func(bool) { updateCost() }
+
+	addRow := func(component *types.RecipeComponent) {
+		row := componentRow{
+			product:  widget.NewSelect(componentNames, func(string) { updateCost() }),
+			quantity: widget.NewEntry(),
+		}
+		row.quantity.SetPlaceHolder("Quantity per unit")
+		row.quantity.OnChanged = func(string) { updateCost() }
+		if component != nil {
+			row.product.SetSelected(component.Component.Name)
+			row.quantity.SetText(strconv.FormatFloat(component.Quantity, 'f', -1, 64))
+		}
+		rows = append(rows, row)
+		rowsBox.Add(container.NewGridWithColumns(2, row.product, row.quantity))
+	}
+	for idx := range recipe {
+		addRow(&recipe[idx])
+	}
+	if len(recipe) == 0 {
+		addRow(nil)
+	}
+	updateCost()
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle("Components per unit", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		rowsBox,
+		widget.NewButton("Add Component", func() { addRow(nil) }),
+		widget.NewSeparator(),
+		trackCheck,
+		costLabel,
+	)
+
+	recipeDialog := dialog.NewCustomConfirm(fmt.Sprintf("Recipe - %s", product.Name), "Save", "Cancel",
+		container.NewVScroll(content),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			parsed, err := readRows()
+			if err != nil {
+				dialog.ShowError(err, i.window)
+				return
+			}
+
+			// A product without a recipe always holds its own stock
+			trackStock := trackCheck.Checked || len(parsed) == 0
+			if err := db.SetRecipe(i.database, product.ID, parsed, trackStock); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to save recipe: %v", err), i.window)
+				return
+			}
+
+			i.refreshProducts()
+		}, i.window)
+	recipeDialog.Resize(fyne.NewSize(600, 450))
+	recipeDialog.Show()
+}
+
+func (i *InventoryWindow) showAddVariantDialog(parent types.Product) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. Large")
+	skuEntry := widget.NewEntry()
+	priceEntry := widget.NewEntry()
+	priceEntry.SetText(fmt.Sprintf("%.2f", parent.Price))
+	costEntry := widget.NewEntry()
+	costEntry.SetText(fmt.Sprintf("%.2f", parent.Cost))
+	stockEntry := widget.NewEntry()
+	stockEntry.SetText("0")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Variant", nameEntry),
+		widget.NewFormItem("SKU", skuEntry),
+		widget.NewFormItem("Price", priceEntry),
+		widget.NewFormItem("Cost", costEntry),
+		widget.NewFormItem("Stock", stockEntry),
+	}
+
+	dialog.ShowForm("Add Variant of "+parent.Name, "Add", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			price, err := strconv.ParseFloat(priceEntry.Text, 64)
+			if err != nil || price < 0 {
+				dialog.ShowError(fmt.Errorf("invalid price format"), i.window)
+				return
+			}
+
+			cost, err := parseCost(costEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, i.window)
+				return
+			}
+
+			stock, err := strconv.ParseFloat(stockEntry.Text, 64)
+			if err != nil || stock < 0 {
+				dialog.ShowError(fmt.Errorf("invalid stock format"), i.window)
+				return
+			}
+
+			variant := types.Product{
+				SKU:             skuEntry.Text,
+				VariantName:     strings.TrimSpace(nameEntry.Text),
+				Price:           price,
+				Cost:            cost,
+				ReorderPoint:    parent.ReorderPoint,
+				ReorderQuantity: parent.ReorderQuantity,
+			}
+			variant.Stock = parent.RoundQuantity(stock)
+
+			if err := db.AddVariant(i.database, parent, variant, currentUser); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to add variant: %v", err), i.window)
+				return
+			}
+
+			i.refreshProducts()
+		}, i.window)
+}
+
+// showModifierGroups manages the modifier groups that can be offered on
+// products, such as milk choices or extra shots
+func (i *InventoryWindow) showModifierGroups() {
+	groups, err := db.GetModifierGroups(i.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load modifiers: %v", err), i.window)
+		return
+	}
+
+	var d dialog.Dialog
+	list := container.NewVBox()
+	for _, group := range groups {
+		g := group
+		text := fmt.Sprintf("%s (choose %d-%d)\n", g.Name, g.MinSelect, g.MaxSelect)
+		for _, o := range g.Options {
+			text += fmt.Sprintf("  %-20s +Rp%.2f\n", o.Name, o.PriceDelta)
+		}
+
+		deleteButton := widget.NewButton("Delete", func() {
+			dialog.ShowConfirm("Delete Modifier Group",
+				fmt.Sprintf("Delete %s? It will no longer be offered on any product.", g.Name),
+				func(confirm bool) {
+					if !confirm {
+						return
+					}
+					if err := db.DeleteModifierGroup(i.database, g.ID); err != nil {
+						dialog.ShowError(fmt.Errorf("failed to delete modifier group: %v", err), i.window)
+						return
+					}
+					d.Hide()
+					i.showModifierGroups()
+				}, i.window)
+		})
+
+		list.Add(container.NewBorder(nil, nil, nil, deleteButton, widget.NewTextGridFromString(text)))
+	}
+	if len(groups) == 0 {
+		list.Add(widget.NewLabel("No modifier groups"))
+	}
+
+	addButton := widget.NewButton("New Group", func() {
+		d.Hide()
+		i.showAddModifierGroupDialog()
+	})
+	addButton.Importance = widget.HighImportance
+
+	d = dialog.NewCustom("Modifier Groups", "Close",
+		container.NewBorder(nil, addButton, nil, nil, container.NewVScroll(list)), i.window)
+	d.Resize(fyne.NewSize(500, 450))
+	d.Show()
+}
+
+func (i *InventoryWindow) showAddModifierGroupDialog() {
+	nameEntry := widget.NewEntry()
+	minEntry := widget.NewEntry()
+	minEntry.SetText("0")
+	maxEntry := widget.NewEntry()
+	maxEntry.SetText("1")
+	optionsEntry := widget.NewMultiLineEntry()
+	optionsEntry.SetPlaceHolder("One option per line, e.g.\nOat milk = 5000\nLess sugar")
+	optionsEntry.SetMinRowsVisible(5)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Min Choices", minEntry),
+		widget.NewFormItem("Max Choices", maxEntry),
+		widget.NewFormItem("Options", optionsEntry),
+	}
+
+	dialog.ShowForm("New Modifier Group", "Add", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			group := types.ModifierGroup{Name: strings.TrimSpace(nameEntry.Text)}
+			if group.Name == "" {
+				dialog.ShowError(fmt.Errorf("name is required"), i.window)
+				return
+			}
+
+			var err error
+			group.MinSelect, err = strconv.Atoi(minEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid minimum"), i.window)
+				return
+			}
+			group.MaxSelect, err = strconv.Atoi(maxEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid maximum"), i.window)
+				return
+			}
+
+			for _, line := range strings.Split(optionsEntry.Text, "\n") {
+				name, priceText, _ := strings.Cut(line, "=")
+				option := types.ModifierOption{Name: strings.TrimSpace(name)}
+				if option.Name == "" {
+					continue
+				}
+				if priceText = strings.TrimSpace(priceText); priceText != "" {
+					option.PriceDelta, err = strconv.ParseFloat(priceText, 64)
+					if err != nil {
+						dialog.ShowError(fmt.Errorf("invalid price for %s", option.Name), i.window)
+						return
+					}
+				}
+				group.Options = append(group.Options, option)
+			}
+			if len(group.Options) == 0 {
+				dialog.ShowError(fmt.Errorf("add at least one option"), i.window)
+				return
+			}
+
+			if err := db.AddModifierGroup(i.database, group); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to add modifier group: %v", err), i.window)
+				return
+			}
+
+			i.showModifierGroups()
+		}, i.window)
+}
+
+// applyFilter updates the visible products from the full product list
+func (i *InventoryWindow) applyFilter() {
+	i.products = nil
+	for _, product := range i.allProducts {
+		if product.Active == i.showArchived {
+			continue
+		}
+		if i.lowStockOnly && !product.IsLowStock() {
+			continue
+		}
+		i.products = append(i.products, product)
+	}
+}
+
 func (i *InventoryWindow) refreshProducts() {
 	var err error
-	i.products, err = db.GetProducts(i.database)
+	i.allProducts, err = db.GetAllProducts(i.database)
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("failed to refresh products: %v", err), i.window)
 		return
 	}
+	i.applyFilter()
 	i.list.Refresh()
 }
+
+// parseReorderSettings parses the reorder point and quantity fields, treating
+// empty fields as zero
+func parseReorderSettings(pointText, quantityText string) (float64, float64, error) {
+	var point, quantity float64
+	var err error
+	if pointText != "" {
+		point, err = strconv.ParseFloat(pointText, 64)
+		if err != nil || point < 0 {
+			return 0, 0, fmt.Errorf("invalid reorder point")
+		}
+	}
+	if quantityText != "" {
+		quantity, err = strconv.ParseFloat(quantityText, 64)
+		if err != nil || quantity < 0 {
+			return 0, 0, fmt.Errorf("invalid reorder quantity")
+		}
+	}
+	return point, quantity, nil
+}
+
+// parseCost parses a cost price, treating a blank entry as unknown
+func parseCost(text string) (float64, error) {
+	if strings.TrimSpace(text) == "" {
+		return 0, nil
+	}
+	cost, err := strconv.ParseFloat(text, 64)
+	if err != nil || cost < 0 {
+		return 0, fmt.Errorf("invalid cost format")
+	}
+	return cost, nil
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 
 	"fyne.io/fyne/v2"
@@ -11,6 +12,7 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/hendrisulistya/cashier-app/db"
 )
 
 type MainWindow struct {
@@ -44,6 +46,17 @@ func (m *MainWindow) createMainMenu() fyne.CanvasObject {
 		),
 	)
 
+	// Flag low stock on the inventory button
+	inventoryLabel := "Inventory"
+	inventoryIcon := theme.ListIcon()
+	lowStock, err := db.CountLowStockProducts(m.database)
+	if err != nil {
+		log.Printf("Error counting low stock products: %v", err)
+	} else if lowStock > 0 {
+		inventoryLabel = fmt.Sprintf("Inventory (%d low stock)", lowStock)
+		inventoryIcon = theme.WarningIcon()
+	}
+
 	// Create menu grid with modern styling
 	menuGrid := container.NewGridWithColumns(2,
 		createMenuButton("Cashier", theme.ListIcon(), func() {
@@ -53,7 +66,7 @@ func (m *MainWindow) createMainMenu() fyne.CanvasObject {
 			}
 		}),
 
-		createMenuButton("Inventory", theme.ListIcon(), func() {
+		createMenuButton(inventoryLabel, inventoryIcon, func() {
 			inventoryWindow := NewInventoryWindow(m.window, m.database)
 			if err := inventoryWindow.Load(); err != nil {
 				log.Printf("Error loading inventory window: %v", err)
@@ -61,6 +74,14 @@ func (m *MainWindow) createMainMenu() fyne.CanvasObject {
 			}
 		}),
 
+		createMenuButton("Purchasing", theme.DownloadIcon(), func() {
+			purchasingWindow := NewPurchasingWindow(m.window, m.database)
+			if err := purchasingWindow.Load(); err != nil {
+				log.Printf("Error loading purchasing window: %v", err)
+				dialog.ShowError(err, m.window)
+			}
+		}),
+
 		createMenuButton("Reports", theme.DocumentIcon(), func() {
 			reportsWindow := NewReportWindow(m.window, m.database)
 			if err := reportsWindow.Load(); err != nil {
@@ -82,6 +103,7 @@ func (m *MainWindow) createMainMenu() fyne.CanvasObject {
 	logoutButton := widget.NewButtonWithIcon("Logout", theme.LogoutIcon(), func() {
 		loginPage := NewLoginPage(m.window, func(username, password string) bool {
 			if username == "admin" && password == "admin" {
+				SetCurrentUser(username)
 				mainWindow := NewMainWindow(m.window, m.database)
 				if err := mainWindow.Load(); err != nil {
 					log.Printf("Error loading main window: %v", err)
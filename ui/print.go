@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// printDocument sends a plain-text document such as an invoice or count sheet
+// to the receipt printer. The kind is used to name the output.
+func printDocument(window fyne.Window, kind string, document string) {
+	// TODO: Add printer configuration from settings
+	// Example structure:
+	// printerConfig, err := db.GetPrinterSettings(database)
+	// if err != nil {
+	//     dialog.ShowError(fmt.Errorf("failed to get printer settings: %v", err), window)
+	//     return
+	// }
+
+	// TODO: Implement actual printer integration
+	// Common options include:
+	// 1. ESC/POS for thermal receipt printers
+	// 2. CUPS for Unix-like systems
+	// 3. Windows Printer API for Windows systems
+	// Example:
+	// err := printer.Print(printerConfig, document)
+
+	// Temporary solution: save to file
+	filename := fmt.Sprintf("%s_%s.txt", kind, time.Now().Format("20060102150405"))
+	err := os.WriteFile(filename, []byte(document), 0644)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to save %s: %v", kind, err), window)
+		return
+	}
+
+	dialog.ShowInformation("Success",
+		fmt.Sprintf("Saved to %s", filename),
+		window,
+	)
+}
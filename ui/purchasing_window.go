@@ -0,0 +1,552 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hendrisulistya/cashier-app/db"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+type PurchasingWindow struct {
+	window       fyne.Window
+	database     *sql.DB
+	orderList    *widget.List
+	supplierList *widget.List
+	orders       []types.PurchaseOrder
+	suppliers    []types.Supplier
+	showAll      bool
+}
+
+func NewPurchasingWindow(window fyne.Window, database *sql.DB) *PurchasingWindow {
+	return &PurchasingWindow{
+		window:   window,
+		database: database,
+	}
+}
+
+func (p *PurchasingWindow) Load() error {
+	var err error
+	p.orders, err = db.GetPurchaseOrders(p.database, !p.showAll)
+	if err != nil {
+		return fmt.Errorf("could not fetch purchase orders: %v", err)
+	}
+
+	p.suppliers, err = db.GetSuppliers(p.database)
+	if err != nil {
+		return fmt.Errorf("could not fetch suppliers: %v", err)
+	}
+
+	content := p.createPurchasingContent()
+	p.window.SetContent(content)
+	return nil
+}
+
+func (p *PurchasingWindow) createPurchasingContent() fyne.CanvasObject {
+	// Back button
+	backButton := widget.NewButton("Back to Menu", func() {
+		mainWindow := NewMainWindow(p.window, p.database)
+		if err := mainWindow.Load(); err != nil {
+			log.Printf("Error returning to main menu: %v", err)
+		}
+	})
+
+	// Header
+	header := container.NewHBox(
+		backButton,
+		widget.NewLabel("Purchasing"),
+	)
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Purchase Orders", p.createOrdersTab()),
+		container.NewTabItem("Suppliers", p.createSuppliersTab()),
+	)
+
+	return container.NewBorder(header, nil, nil, nil, tabs)
+}
+
+func (p *PurchasingWindow) createOrdersTab() fyne.CanvasObject {
+	p.orderList = widget.NewList(
+		func() int { return len(p.orders) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabel(""),                    // PO number and supplier
+				widget.NewLabel(""),                    // Status
+				widget.NewLabel(""),                    // Outstanding lines
+				widget.NewButton("View", func() {}),    // View button placeholder
+				widget.NewButton("Receive", func() {}), // Receive button placeholder
+				widget.NewButton("Cancel", func() {}),  // Cancel button placeholder
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			order := p.orders[id]
+			box := item.(*fyne.Container)
+
+			outstanding := 0
+			for _, line := range order.Lines {
+				if line.Outstanding() > 0 {
+					outstanding++
+				}
+			}
+
+			box.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s - %s", order.Number, order.SupplierName))
+			box.Objects[1].(*widget.Label).SetText(order.Status)
+			box.Objects[2].(*widget.Label).SetText(fmt.Sprintf("%d of %d lines outstanding", outstanding, len(order.Lines)))
+
+			box.Objects[3].(*widget.Button).OnTapped = func() {
+				p.showOrderDetails(order)
+			}
+
+			isOpen := order.Status == types.POStatusOpen || order.Status == types.POStatusPartial
+			receiveButton := box.Objects[4].(*widget.Button)
+			receiveButton.OnTapped = func() {
+				p.showReceiveDialog(order)
+			}
+			cancelButton := box.Objects[5].(*widget.Button)
+			cancelButton.OnTapped = func() {
+				p.showCancelDialog(order)
+			}
+			if isOpen {
+				receiveButton.Enable()
+				cancelButton.Enable()
+			} else {
+				receiveButton.Disable()
+				cancelButton.Disable()
+			}
+		},
+	)
+
+	showAllCheck := widget.NewCheck("Show received and cancelled orders", func(checked bool) {
+		p.showAll = checked
+		p.refresh()
+	})
+	showAllCheck.SetChecked(p.showAll)
+
+	newOrderButton := widget.NewButton("New Purchase Order", func() {
+		p.showNewOrderDialog()
+	})
+	newOrderButton.Importance = widget.HighImportance
+
+	return container.NewBorder(
+		showAllCheck,
+		newOrderButton,
+		nil,
+		nil,
+		container.NewScroll(p.orderList),
+	)
+}
+
+func (p *PurchasingWindow) createSuppliersTab() fyne.CanvasObject {
+	p.supplierList = widget.NewList(
+		func() int { return len(p.suppliers) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabel(""),                 // Supplier name
+				widget.NewLabel(""),                 // Contact
+				widget.NewLabel(""),                 // Phone
+				widget.NewButton("Edit", func() {}), // Edit button placeholder
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			supplier := p.suppliers[id]
+			box := item.(*fyne.Container)
+
+			box.Objects[0].(*widget.Label).SetText(supplier.Name)
+			box.Objects[1].(*widget.Label).SetText(supplier.ContactName)
+			box.Objects[2].(*widget.Label).SetText(supplier.Phone)
+			box.Objects[3].(*widget.Button).OnTapped = func() {
+				p.showSupplierDialog(&supplier)
+			}
+		},
+	)
+
+	addButton := widget.NewButton("Add New Supplier", func() {
+		p.showSupplierDialog(nil)
+	})
+	addButton.Importance = widget.HighImportance
+
+	return container.NewBorder(nil, addButton, nil, nil, container.NewScroll(p.supplierList))
+}
+
+// showSupplierDialog adds a new supplier, or edits the given one
+func (p *PurchasingWindow) showSupplierDialog(supplier *types.Supplier) {
+	nameEntry := widget.NewEntry()
+	contactEntry := widget.NewEntry()
+	phoneEntry := widget.NewEntry()
+	emailEntry := widget.NewEntry()
+	addressEntry := widget.NewMultiLineEntry()
+
+	title := "Add New Supplier"
+	if supplier != nil {
+		title = "Edit Supplier"
+		nameEntry.SetText(supplier.Name)
+		contactEntry.SetText(supplier.ContactName)
+		phoneEntry.SetText(supplier.Phone)
+		emailEntry.SetText(supplier.Email)
+		addressEntry.SetText(supplier.Address)
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Contact", contactEntry),
+		widget.NewFormItem("Phone", phoneEntry),
+		widget.NewFormItem("Email", emailEntry),
+		widget.NewFormItem("Address", addressEntry),
+	}
+
+	dialog.ShowForm(title, "Save", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			if nameEntry.Text == "" {
+				dialog.ShowError(fmt.Errorf("supplier name is required"), p.window)
+				return
+			}
+
+			updated := types.Supplier{
+				Name:        nameEntry.Text,
+				ContactName: contactEntry.Text,
+				Phone:       phoneEntry.Text,
+				Email:       emailEntry.Text,
+				Address:     addressEntry.Text,
+			}
+
+			var err error
+			if supplier == nil {
+				err = db.AddSupplier(p.database, updated)
+			} else {
+				updated.ID = supplier.ID
+				err = db.UpdateSupplier(p.database, updated)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to save supplier: %v", err), p.window)
+				return
+			}
+
+			p.refresh()
+		}, p.window)
+}
+
+func (p *PurchasingWindow) showNewOrderDialog() {
+	if len(p.suppliers) == 0 {
+		dialog.ShowError(fmt.Errorf("add a supplier before creating a purchase order"), p.window)
+		return
+	}
+
+	products, err := db.GetProducts(p.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("could not fetch products: %v", err), p.window)
+		return
+	}
+
+	supplierNames := make([]string, len(p.suppliers))
+	for i, s := range p.suppliers {
+		supplierNames[i] = s.Name
+	}
+	productNames := make([]string, len(products))
+	for i, product := range products {
+		productNames[i] = product.Name
+	}
+
+	supplierSelect := widget.NewSelect(supplierNames, nil)
+	expectedEntry := widget.NewEntry()
+	expectedEntry.SetPlaceHolder("Expected delivery (YYYY-MM-DD)")
+	noteEntry := widget.NewEntry()
+	noteEntry.SetPlaceHolder("Note")
+
+	// Each line is a product, quantity and expected unit cost
+	type orderLineRow struct {
+		product  *widget.Select
+		quantity *widget.Entry
+		cost     *widget.Entry
+	}
+	var lineRows []orderLineRow
+	linesBox := container.NewVBox()
+
+	addLine := func() {
+		row := orderLineRow{
+			product:  widget.NewSelect(productNames, nil),
+			quantity: widget.NewEntry(),
+			cost:     widget.NewEntry(),
+		}
+		row.quantity.SetPlaceHolder("Quantity")
+		row.cost.SetPlaceHolder("Unit cost")
+		lineRows = append(lineRows, row)
+		linesBox.Add(container.NewGridWithColumns(3, row.product, row.quantity, row.cost))
+	}
+	addLine()
+
+	content := container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem("Supplier", supplierSelect),
+			widget.NewFormItem("Expected", expectedEntry),
+			widget.NewFormItem("Note", noteEntry),
+		),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Lines", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		linesBox,
+		widget.NewButton("Add Line", addLine),
+	)
+
+	orderDialog := dialog.NewCustomConfirm("New Purchase Order", "Create", "Cancel",
+		container.NewVScroll(content),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			if supplierSelect.SelectedIndex() < 0 {
+				dialog.ShowError(fmt.Errorf("please select a supplier"), p.window)
+				return
+			}
+
+			order := types.PurchaseOrder{
+				SupplierID: p.suppliers[supplierSelect.SelectedIndex()].ID,
+				Note:       noteEntry.Text,
+			}
+
+			if expectedEntry.Text != "" {
+				expected, err := time.Parse("2006-01-02", expectedEntry.Text)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("invalid expected date format"), p.window)
+					return
+				}
+				order.ExpectedDate = &expected
+			}
+
+			for _, row := range lineRows {
+				if row.product.SelectedIndex() < 0 {
+					continue
+				}
+				product := products[row.product.SelectedIndex()]
+
+				quantity, err := strconv.ParseFloat(row.quantity.Text, 64)
+				if err != nil || quantity <= 0 {
+					dialog.ShowError(fmt.Errorf("invalid quantity for %s", product.Name), p.window)
+					return
+				}
+
+				cost := 0.0
+				if row.cost.Text != "" {
+					cost, err = strconv.ParseFloat(row.cost.Text, 64)
+					if err != nil || cost < 0 {
+						dialog.ShowError(fmt.Errorf("invalid unit cost for %s", product.Name), p.window)
+						return
+					}
+				}
+
+				order.Lines = append(order.Lines, types.PurchaseOrderLine{
+					Product:         product,
+					QuantityOrdered: product.RoundQuantity(quantity),
+					UnitCost:        cost,
+				})
+			}
+
+			number, err := db.CreatePurchaseOrder(p.database, order, currentUser)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to create purchase order: %v", err), p.window)
+				return
+			}
+
+			dialog.ShowInformation("Success", fmt.Sprintf("Purchase order %s created", number), p.window)
+			p.refresh()
+		}, p.window)
+	orderDialog.Resize(fyne.NewSize(600, 500))
+	orderDialog.Show()
+}
+
+func (p *PurchasingWindow) showOrderDetails(order types.PurchaseOrder) {
+	details := fmt.Sprintf("Purchase Order: %s\n", order.Number)
+	details += fmt.Sprintf("Supplier: %s\n", order.SupplierName)
+	details += fmt.Sprintf("Status: %s\n", order.Status)
+	details += fmt.Sprintf("Created: %s by %s\n", order.CreatedAt.Format("2006-01-02 15:04"), order.CreatedBy)
+	if order.ExpectedDate != nil {
+		details += fmt.Sprintf("Expected: %s\n", order.ExpectedDate.Format("2006-01-02"))
+	}
+	if order.Note != "" {
+		details += fmt.Sprintf("Note: %s\n", order.Note)
+	}
+	details += "\n"
+	details += fmt.Sprintf("%-20s | %10s | %10s | %11s | %s\n", "Product", "Ordered", "Received", "Outstanding", "Unit Cost")
+	details += "---------------------------------------------------------------------------\n"
+
+	var total float64
+	for _, line := range order.Lines {
+		details += fmt.Sprintf("%-20s | %10s | %10s | %11s | Rp%.2f\n",
+			line.Product.Name,
+			line.Product.FormatQuantity(line.QuantityOrdered),
+			line.Product.FormatQuantity(line.QuantityReceived),
+			line.Product.FormatQuantity(line.Outstanding()),
+			line.UnitCost)
+		total += line.QuantityOrdered * line.UnitCost
+	}
+	details += "---------------------------------------------------------------------------\n"
+	details += fmt.Sprintf("Expected Cost: Rp%.2f\n", total)
+
+	scroll := container.NewScroll(widget.NewTextGridFromString(details))
+	scroll.SetMinSize(fyne.NewSize(700, 400))
+	dialog.ShowCustom(order.Number, "Close", scroll, p.window)
+}
+
+func (p *PurchasingWindow) showReceiveDialog(order types.PurchaseOrder) {
+	type receiveRow struct {
+		line     types.PurchaseOrderLine
+		quantity *widget.Entry
+		unit     *widget.Select
+		cost     *widget.Entry
+		lot      *widget.Entry
+		expiry   *widget.Entry
+		// units holds the base units in each choice of the unit select
+		units []float64
+	}
+
+	packages, err := db.GetPackages(p.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("could not fetch packaging units: %v", err), p.window)
+		return
+	}
+
+	var rows []receiveRow
+	var items []*widget.FormItem
+	for _, line := range order.Lines {
+		if line.Outstanding() <= 0 {
+			continue
+		}
+
+		// Default to receiving everything outstanding at the ordered cost
+		quantityEntry := widget.NewEntry()
+		quantityEntry.SetText(types.FormatQuantity(line.Outstanding(), line.Product.Precision))
+		costEntry := widget.NewEntry()
+		costEntry.SetText(fmt.Sprintf("%.2f", line.UnitCost))
+
+		// Lot number and expiry are optional and only needed for perishables
+		lotEntry := widget.NewEntry()
+		lotEntry.SetPlaceHolder("Lot no.")
+		expiryEntry := widget.NewEntry()
+		expiryEntry.SetPlaceHolder("Expiry YYYY-MM-DD")
+
+		// Goods can be received in the base unit or any packaging unit, with
+		// quantity and cost converted to base units on receipt
+		unitNames := []string{line.Product.Unit}
+		units := []float64{1}
+		for _, pkg := range packages[line.Product.ID] {
+			unitNames = append(unitNames, pkg.Name)
+			units = append(units, pkg.Units)
+		}
+		unitSelect := widget.NewSelect(unitNames, nil)
+		unitSelect.SetSelectedIndex(0)
+		unitSelect.OnChanged = func(string) {
+			perUnit := units[unitSelect.SelectedIndex()]
+			quantityEntry.SetText(strconv.FormatFloat(line.Outstanding()/perUnit, 'f', -1, 64))
+			costEntry.SetText(fmt.Sprintf("%.2f", line.UnitCost*perUnit))
+		}
+
+		rows = append(rows, receiveRow{line: line, quantity: quantityEntry, unit: unitSelect, cost: costEntry,
+			lot: lotEntry, expiry: expiryEntry, units: units})
+		items = append(items, widget.NewFormItem(
+			fmt.Sprintf("%s (%s due)", line.Product.Name, line.Product.FormatQuantity(line.Outstanding())),
+			container.NewGridWithColumns(5, quantityEntry, unitSelect, costEntry, lotEntry, expiryEntry),
+		))
+	}
+
+	noteEntry := widget.NewEntry()
+	noteEntry.SetPlaceHolder("Delivery note or reference")
+	items = append(items, widget.NewFormItem("Note", noteEntry))
+
+	dialog.ShowForm(fmt.Sprintf("Receive %s", order.Number), "Receive", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			var lines []types.ReceiptLine
+			for _, row := range rows {
+				quantity, err := strconv.ParseFloat(row.quantity.Text, 64)
+				if err != nil || quantity < 0 {
+					dialog.ShowError(fmt.Errorf("invalid quantity for %s", row.line.Product.Name), p.window)
+					return
+				}
+
+				cost, err := strconv.ParseFloat(row.cost.Text, 64)
+				if err != nil || cost < 0 {
+					dialog.ShowError(fmt.Errorf("invalid unit cost for %s", row.line.Product.Name), p.window)
+					return
+				}
+
+				var expiry *time.Time
+				if text := strings.TrimSpace(row.expiry.Text); text != "" {
+					date, err := time.Parse("2006-01-02", text)
+					if err != nil {
+						dialog.ShowError(fmt.Errorf("invalid expiry date for %s", row.line.Product.Name), p.window)
+						return
+					}
+					expiry = &date
+				}
+
+				perUnit := row.units[row.unit.SelectedIndex()]
+				lines = append(lines, types.ReceiptLine{
+					PurchaseOrderLineID: row.line.ID,
+					Quantity:            row.line.Product.RoundQuantity(quantity * perUnit),
+					UnitCost:            cost / perUnit,
+					LotNumber:           strings.TrimSpace(row.lot.Text),
+					ExpiryDate:          expiry,
+				})
+			}
+
+			if err := db.ReceiveGoods(p.database, order, lines, currentUser, noteEntry.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to receive goods: %v", err), p.window)
+				return
+			}
+
+			p.refresh()
+		}, p.window)
+}
+
+func (p *PurchasingWindow) showCancelDialog(order types.PurchaseOrder) {
+	dialog.ShowConfirm("Cancel Purchase Order",
+		fmt.Sprintf("Are you sure you want to cancel %s? Quantities not yet received will no longer be expected.", order.Number),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			if err := db.CancelPurchaseOrder(p.database, order.ID); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to cancel purchase order: %v", err), p.window)
+				return
+			}
+
+			p.refresh()
+		}, p.window)
+}
+
+func (p *PurchasingWindow) refresh() {
+	var err error
+	p.orders, err = db.GetPurchaseOrders(p.database, !p.showAll)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to refresh purchase orders: %v", err), p.window)
+		return
+	}
+
+	p.suppliers, err = db.GetSuppliers(p.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to refresh suppliers: %v", err), p.window)
+		return
+	}
+
+	if p.orderList != nil {
+		p.orderList.Refresh()
+	}
+	if p.supplierList != nil {
+		p.supplierList.Refresh()
+	}
+}
@@ -4,20 +4,28 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/hendrisulistya/cashier-app/db"
+	"github.com/hendrisulistya/cashier-app/reports"
+	"github.com/hendrisulistya/cashier-app/types"
 	datepicker "github.com/sdassow/fyne-datepicker"
 )
 
 type ReportWindow struct {
 	window   fyne.Window
 	database *sql.DB
+	// location is the store time zone that report days are counted in
+	location *time.Location
 }
 
 func NewReportWindow(window fyne.Window, database *sql.DB) *ReportWindow {
@@ -28,6 +36,12 @@ func NewReportWindow(window fyne.Window, database *sql.DB) *ReportWindow {
 }
 
 func (r *ReportWindow) Load() error {
+	settings, err := db.GetSettings(r.database)
+	if err != nil {
+		return fmt.Errorf("could not load settings: %v", err)
+	}
+	r.location = settings.Location()
+
 	content := r.createReportContent()
 	r.window.SetContent(content)
 	return nil
@@ -76,13 +90,70 @@ func (r *ReportWindow) createReportContent() fyne.CanvasObject {
 		dialog.ShowCustom("Select End Date", "Done", picker, r.window)
 	})
 
-	// Today button with icon
-	todayButton := widget.NewButtonWithIcon("Today", theme.MediaPlayIcon(), func() {
-		today := time.Now().Format("2006-01-02")
-		startDate.SetText(today)
-		endDate.SetText(today)
+	// Presets fill in the dates; editing either date switches to Custom
+	applyingPreset := false
+	rangeSelect := widget.NewSelect(types.RangePresets, func(preset string) {
+		if preset == types.RangeCustom {
+			return
+		}
+		period := types.PresetRange(preset, time.Now(), r.location)
+		applyingPreset = true
+		startDate.SetText(period.Start.Format("2006-01-02"))
+		endDate.SetText(period.LastDay().Format("2006-01-02"))
+		applyingPreset = false
+	})
+	onDateChanged := func(string) {
+		if !applyingPreset {
+			rangeSelect.SetSelected(types.RangeCustom)
+		}
+	}
+	startDate.OnChanged = onDateChanged
+	endDate.OnChanged = onDateChanged
+	rangeSelect.SetSelected(types.RangeToday)
+
+	// Reports can be compared against an earlier period
+	compareStart := widget.NewEntry()
+	compareStart.SetPlaceHolder("Compare from (YYYY-MM-DD)")
+	compareEnd := widget.NewEntry()
+	compareEnd.SetPlaceHolder("Compare to (YYYY-MM-DD)")
+	customCompare := container.NewHBox(
+		widget.NewLabelWithStyle("Compare With:", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		compareStart,
+		compareEnd,
+	)
+	customCompare.Hide()
+	compareSelect := widget.NewSelect(types.ComparisonOptions, func(option string) {
+		if option == types.CompareCustom {
+			customCompare.Show()
+		} else {
+			customCompare.Hide()
+		}
 	})
-	todayButton.Importance = widget.MediumImportance
+	compareSelect.SetSelected(types.CompareNone)
+
+	// comparisonRange returns the period to compare against, or nil when no
+	// comparison is selected
+	comparisonRange := func(period types.TimeRange) (*types.TimeRange, error) {
+		var compare types.TimeRange
+		switch compareSelect.Selected {
+		case types.ComparePrevious:
+			compare = period.Previous()
+		case types.CompareLastYear:
+			compare = period.LastYear()
+		case types.CompareCustom:
+			var err error
+			compare, err = r.parseRange(compareStart.Text, compareEnd.Text)
+			if err != nil {
+				return nil, fmt.Errorf("comparison period: %v", err)
+			}
+		default:
+			return nil, nil
+		}
+		return &compare, nil
+	}
+
+	// Quantities are in base units unless shown in the largest packaging unit
+	packageCheck := widget.NewCheck("Show quantities in packaging units", nil)
 
 	// Date form with better spacing and vertical layout
 	dateForm := container.NewVBox(
@@ -98,9 +169,14 @@ func (r *ReportWindow) createReportContent() fyne.CanvasObject {
 		),
 		container.NewHBox(
 			layout.NewSpacer(),
-			todayButton,
+			widget.NewLabelWithStyle("Period:", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			rangeSelect,
+			widget.NewLabelWithStyle("Compare:", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			compareSelect,
+			packageCheck,
 			layout.NewSpacer(),
 		),
+		customCompare,
 	)
 
 	// Report content with monospace font
@@ -109,100 +185,198 @@ func (r *ReportWindow) createReportContent() fyne.CanvasObject {
 		TextStyle: fyne.TextStyle{Monospace: true},
 	})
 
+	// Charts follow the same date range as the report
+	chartGrid := container.NewGridWithColumns(2)
+
 	// Action buttons with icons
 	generateButton := widget.NewButtonWithIcon("Generate Report", theme.DocumentCreateIcon(), func() {
-		start, err := time.Parse("2006-01-02", startDate.Text)
+		period, err := r.parseRange(startDate.Text, endDate.Text)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+		compare, err := comparisonRange(period)
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("invalid start date format"), r.window)
+			dialog.ShowError(err, r.window)
 			return
 		}
 
-		end, err := time.Parse("2006-01-02", endDate.Text)
+		sales, err := reports.LoadSales(r.database, period, compare)
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("invalid end date format"), r.window)
+			dialog.ShowError(err, r.window)
 			return
 		}
+		if packageCheck.Checked {
+			sales.Packages, err = db.GetPackages(r.database)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to load packaging units: %v", err), r.window)
+				return
+			}
+		}
 
-		report, err := r.generateSalesReport(start, end)
+		reportText.SetText(sales.Text())
+
+		charts, err := r.createCharts(period)
 		if err != nil {
 			dialog.ShowError(err, r.window)
 			return
 		}
-
-		reportText.SetText(report)
+		chartGrid.Objects = charts
+		chartGrid.Refresh()
 	})
 	generateButton.Importance = widget.HighImportance
 
-	exportButton := widget.NewButtonWithIcon("Export to CSV", theme.DocumentSaveIcon(), func() {
-		if startDate.Text == "" || endDate.Text == "" {
-			dialog.ShowError(fmt.Errorf("please select both start and end dates"), r.window)
+	// Dead stock is anything on hand that has not sold within this many days
+	deadStockEntry := widget.NewEntry()
+	deadStockEntry.SetText("30")
+
+	performanceButton := widget.NewButtonWithIcon("Product Performance", theme.ListIcon(), func() {
+		period, err := r.parseRange(startDate.Text, endDate.Text)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+		deadStockDays, err := strconv.Atoi(deadStockEntry.Text)
+		if err != nil || deadStockDays <= 0 {
+			dialog.ShowError(fmt.Errorf("invalid number of days for dead stock"), r.window)
 			return
 		}
 
-		start, err := time.Parse("2006-01-02", startDate.Text)
+		performance, err := reports.LoadPerformance(r.database, period, deadStockDays, time.Now())
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("invalid start date format"), r.window)
+			dialog.ShowError(err, r.window)
 			return
 		}
+		reportText.SetText(performance.Text())
+	})
 
-		end, err := time.Parse("2006-01-02", endDate.Text)
+	cashierButton := widget.NewButtonWithIcon("Cashier Report", theme.AccountIcon(), func() {
+		period, err := r.parseRange(startDate.Text, endDate.Text)
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("invalid end date format"), r.window)
+			dialog.ShowError(err, r.window)
 			return
 		}
 
-		// Query to get sales data
-		query := `
-			SELECT
-				p.name,
-				SUM(si.quantity) as total_quantity,
-				SUM(si.quantity * p.price) as total_sales
-			FROM sales s
-			JOIN sale_items si ON s.id = si.sale_id
-			JOIN products p ON si.product_id = p.id
-			WHERE s.created_at BETWEEN $1 AND $2
-			GROUP BY p.name
-			ORDER BY total_sales DESC
-		`
-
-		rows, err := r.database.Query(query, start, end)
+		cashiers, err := reports.LoadCashiers(r.database, period)
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("failed to query sales data: %v", err), r.window)
+			dialog.ShowError(err, r.window)
 			return
 		}
-		defer rows.Close()
-
-		// Create CSV content
-		csvContent := fmt.Sprintf("Sales Report from %s to %s\n\n", start.Format("2006-01-02"), end.Format("2006-01-02"))
-		csvContent += "Product Name,Quantity,Total Sales\n"
-
-		var totalRevenue float64
-		for rows.Next() {
-			var (
-				name       string
-				quantity   int
-				totalSales float64
-			)
-			if err := rows.Scan(&name, &quantity, &totalSales); err != nil {
-				dialog.ShowError(fmt.Errorf("failed to scan row: %v", err), r.window)
-				return
-			}
-			csvContent += fmt.Sprintf("%s,%d,Rp%.2f\n", name, quantity, totalSales)
-			totalRevenue += totalSales
+		reportText.SetText(cashiers.Text())
+	})
+
+	// Valuation is stock on hand at the end of the range's last day
+	valuationButton := widget.NewButtonWithIcon("Inventory Valuation", theme.StorageIcon(), func() {
+		period, err := r.parseRange(startDate.Text, endDate.Text)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+
+		valuation, err := reports.LoadValuation(r.database, period.LastDay())
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
 		}
+		reportText.SetText(valuation.Text())
+	})
 
-		csvContent += fmt.Sprintf("\nTotal Revenue,Rp%.2f\n", totalRevenue)
+	taxButton := widget.NewButtonWithIcon("Tax Report", theme.DocumentIcon(), func() {
+		period, err := r.parseRange(startDate.Text, endDate.Text)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
 
-		dialog.ShowInformation("Report Generated", csvContent, r.window)
+		tax, err := reports.LoadTax(r.database, period)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+		reportText.SetText(tax.Text())
+	})
+
+	formatSelect := widget.NewSelect([]string{"CSV", "XLSX", "JSON"}, nil)
+	formatSelect.SetSelected("CSV")
+
+	exportButton := widget.NewButtonWithIcon("Export", theme.DocumentSaveIcon(), func() {
+		if startDate.Text == "" || endDate.Text == "" {
+			dialog.ShowError(fmt.Errorf("please select both start and end dates"), r.window)
+			return
+		}
+
+		period, err := r.parseRange(startDate.Text, endDate.Text)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+		compare, err := comparisonRange(period)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+
+		r.showExportDialog("sales", period, strings.ToLower(formatSelect.Selected), func() (reports.Report, error) {
+			return reports.LoadSales(r.database, period, compare)
+		})
 	})
 	exportButton.Importance = widget.MediumImportance
 
+	exportTaxButton := widget.NewButtonWithIcon("Export Tax", theme.DocumentSaveIcon(), func() {
+		period, err := r.parseRange(startDate.Text, endDate.Text)
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+		r.showExportDialog("tax", period, strings.ToLower(formatSelect.Selected), func() (reports.Report, error) {
+			return reports.LoadTax(r.database, period)
+		})
+	})
+
+	// Register reports cover the period since the last Z-report
+	xReportButton := widget.NewButtonWithIcon("X Report", theme.DocumentIcon(), func() {
+		report, err := reports.LoadXReport(r.database, r.location)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error generating X report: %v", err), r.window)
+			return
+		}
+		r.showRegisterReport("x_report", report)
+	})
+
+	zReportButton := widget.NewButtonWithIcon("Z Report (Close Day)", theme.DocumentIcon(), func() {
+		dialog.ShowConfirm("Close Register",
+			"This closes the current period and starts a new one. Continue?",
+			func(confirm bool) {
+				if !confirm {
+					return
+				}
+				report, err := db.CloseRegister(r.database, currentUser)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("error closing register: %v", err), r.window)
+					return
+				}
+				r.showRegisterReport("z_report", reports.NewRegister(report, r.location))
+			}, r.window)
+	})
+	zReportButton.Importance = widget.DangerImportance
+
 	// Button container with spacing
 	buttons := container.NewHBox(
 		layout.NewSpacer(),
 		generateButton,
+		performanceButton,
+		cashierButton,
+		valuationButton,
+		taxButton,
+		widget.NewLabel("Dead stock after (days):"),
+		deadStockEntry,
 		widget.NewLabel(""), // spacing
+		formatSelect,
 		exportButton,
+		exportTaxButton,
+		widget.NewLabel(""), // spacing
+		xReportButton,
+		zReportButton,
 		layout.NewSpacer(),
 	)
 
@@ -211,7 +385,10 @@ func (r *ReportWindow) createReportContent() fyne.CanvasObject {
 		container.NewVBox(
 			widget.NewLabelWithStyle("Report Output", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 			widget.NewSeparator(),
-			container.NewPadded(container.NewScroll(reportText)),
+			container.NewPadded(container.NewAppTabs(
+				container.NewTabItem("Report", container.NewScroll(reportText)),
+				container.NewTabItem("Charts", container.NewVScroll(chartGrid)),
+			)),
 		),
 	)
 
@@ -232,48 +409,121 @@ func (r *ReportWindow) createReportContent() fyne.CanvasObject {
 	return container.NewPadded(content)
 }
 
-func (r *ReportWindow) generateSalesReport(start, end time.Time) (string, error) {
-	// Query to get sales data
-	query := `
-        SELECT
-            p.name,
-            SUM(si.quantity) as total_quantity,
-            SUM(si.quantity * si.price_at_sale) as total_sales
-        FROM sales s
-        JOIN sale_items si ON s.id = si.sale_id
-        JOIN products p ON si.product_id = p.id
-        WHERE s.created_at BETWEEN $1 AND $2
-        GROUP BY p.name
-        ORDER BY total_sales DESC
-    `
-
-	rows, err := r.database.Query(query, start, end)
+// parseRange reads a start and end date as a range covering both days in the
+// store time zone
+func (r *ReportWindow) parseRange(startText, endText string) (types.TimeRange, error) {
+	start, err := time.ParseInLocation("2006-01-02", startText, r.location)
+	if err != nil {
+		return types.TimeRange{}, fmt.Errorf("invalid start date format")
+	}
+	end, err := time.ParseInLocation("2006-01-02", endText, r.location)
 	if err != nil {
-		return "", fmt.Errorf("failed to query sales data: %v", err)
+		return types.TimeRange{}, fmt.Errorf("invalid end date format")
+	}
+	if end.Before(start) {
+		return types.TimeRange{}, fmt.Errorf("end date is before start date")
 	}
-	defer rows.Close()
-
-	var report string
-	report += fmt.Sprintf("Sales Report from %s to %s\n\n", start.Format("2006-01-02"), end.Format("2006-01-02"))
-	report += "Product Name | Quantity | Total Sales\n"
-	report += "----------------------------------------\n"
-
-	var totalRevenue float64
-	for rows.Next() {
-		var (
-			name       string
-			quantity   int
-			totalSales float64
-		)
-		if err := rows.Scan(&name, &quantity, &totalSales); err != nil {
-			return "", fmt.Errorf("failed to scan row: %v", err)
+	return types.NewTimeRange(start, end, r.location), nil
+}
+
+// createCharts draws the revenue trend, best sellers, busiest hours and
+// payment mix for the period
+func (r *ReportWindow) createCharts(period types.TimeRange) ([]fyne.CanvasObject, error) {
+	sales, err := reports.LoadSales(r.database, period, nil)
+	if err != nil {
+		return nil, err
+	}
+	trends, err := reports.LoadTrends(r.database, period)
+	if err != nil {
+		return nil, err
+	}
+
+	var dayLabels []string
+	var dayRevenue []float64
+	for _, d := range trends.Daily {
+		dayLabels = append(dayLabels, d.Day.Format("Jan 2"))
+		dayRevenue = append(dayRevenue, d.Revenue)
+	}
+
+	// Lines are ordered by revenue, so the first ten are the best sellers
+	var productNames []string
+	var productRevenue []float64
+	for i, line := range sales.Lines {
+		if i == 10 {
+			break
 		}
-		report += fmt.Sprintf("%-20s | %8d | Rp%.2f\n", name, quantity, totalSales)
-		totalRevenue += totalSales
+		productNames = append(productNames, line.Product.Name)
+		productRevenue = append(productRevenue, line.Revenue)
+	}
+
+	weekdays := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	hours := make([]string, 24)
+	for h := 0; h < 24; h += 3 {
+		hours[h] = strconv.Itoa(h)
+	}
+	byHour := make([][]float64, len(trends.ByHour))
+	for i := range trends.ByHour {
+		byHour[i] = trends.ByHour[i][:]
+	}
+
+	var methods []string
+	var taken []float64
+	for _, p := range trends.Payments {
+		methods = append(methods, strings.ToUpper(p.Method))
+		taken = append(taken, p.Taken)
 	}
 
-	report += "----------------------------------------\n"
-	report += fmt.Sprintf("Total Revenue: Rp%.2f\n", totalRevenue)
+	return []fyne.CanvasObject{
+		widget.NewCard("Daily Revenue", "", newLineChart(dayLabels, dayRevenue)),
+		widget.NewCard("Top Products", "", newBarChart(productNames, productRevenue)),
+		widget.NewCard("Sales by Hour", "", newHeatmap(weekdays, hours, byHour)),
+		widget.NewCard("Payment Methods", "", newPieChart(methods, taken)),
+	}, nil
+}
 
-	return report, nil
+// showExportDialog saves the report returned by load to a file chosen by the
+// user. The suggested file name starts with name and the period.
+func (r *ReportWindow) showExportDialog(name string, period types.TimeRange, format string, load func() (reports.Report, error)) {
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		report, err := load()
+		if err != nil {
+			dialog.ShowError(err, r.window)
+			return
+		}
+		if err := reports.Render(writer, format, report); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to export report: %v", err), r.window)
+			return
+		}
+		dialog.ShowInformation("Export Complete", fmt.Sprintf("Report exported to %s", writer.URI().Path()), r.window)
+	}, r.window)
+	saveDialog.SetFileName(fmt.Sprintf("%s_%s_%s.%s", name,
+		period.Start.Format("20060102"), period.LastDay().Format("20060102"), format))
+	saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{"." + format}))
+	saveDialog.Show()
+}
+
+// showRegisterReport displays an X or Z report with the option to print it
+func (r *ReportWindow) showRegisterReport(kind string, report reports.Register) {
+	text := report.Text()
+	scroll := container.NewScroll(widget.NewTextGridFromString(text))
+	scroll.SetMinSize(fyne.NewSize(400, 500))
+
+	title := "X Report"
+	if report.Number != "" {
+		title = "Z Report " + report.Number
+	}
+	dialog.ShowCustomConfirm(title, "Print", "Close", scroll, func(print bool) {
+		if print {
+			printDocument(r.window, kind, text)
+		}
+	}, r.window)
 }
@@ -0,0 +1,10 @@
+package ui
+
+// currentUser is the user logged in on this terminal. It is recorded against
+// sales and stock changes.
+var currentUser string
+
+// SetCurrentUser records the user who just logged in
+func SetCurrentUser(username string) {
+	currentUser = username
+}
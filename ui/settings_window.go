@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -118,6 +119,36 @@ func (s *SettingsWindow) createSettingsContent() fyne.CanvasObject {
 	paperWidthEntry.SetText(paperWidth)
 	paperWidthEntry.SetPlaceHolder("Enter paper width in inches")
 
+	// Loyalty Settings
+	loyaltyEnabledCheck := widget.NewCheck("Enable loyalty points", nil)
+	loyaltyEnabledCheck.SetChecked(settings.LoyaltyEnabled)
+
+	loyaltyEarnEntry := widget.NewEntry()
+	loyaltyEarnEntry.SetText(fmt.Sprintf("%.2f", settings.LoyaltyEarnAmount))
+	loyaltyEarnEntry.SetPlaceHolder("Spend required to earn one point")
+
+	loyaltyValueEntry := widget.NewEntry()
+	loyaltyValueEntry.SetText(fmt.Sprintf("%.2f", settings.LoyaltyPointValue))
+	loyaltyValueEntry.SetPlaceHolder("Value of one point when redeemed")
+
+	loyaltyModeSelect := widget.NewSelect([]string{"discount", "payment"}, nil)
+	loyaltyModeSelect.SetSelected(settings.LoyaltyRedeemMode)
+
+	// Supervisor PIN used to approve stock corrections
+	supervisorPINEntry := widget.NewPasswordEntry()
+	supervisorPINEntry.SetText(settings.SupervisorPIN)
+	supervisorPINEntry.SetPlaceHolder("Enter supervisor PIN")
+
+	// Expiry Settings
+	expiryLookaheadEntry := widget.NewEntry()
+	expiryLookaheadEntry.SetText(strconv.Itoa(settings.ExpiryLookaheadDays))
+	expiryLookaheadEntry.SetPlaceHolder("Days ahead to warn about expiring lots")
+
+	// Time Zone Settings
+	timeZoneEntry := widget.NewEntry()
+	timeZoneEntry.SetText(settings.TimeZone)
+	timeZoneEntry.SetPlaceHolder("e.g. Asia/Jakarta")
+
 	// Save Button
 	saveButton := widget.NewButton("Save Settings", func() {
 		// Validate tax percentage
@@ -127,6 +158,34 @@ func (s *SettingsWindow) createSettingsContent() fyne.CanvasObject {
 			return
 		}
 
+		// Validate loyalty amounts
+		earnAmount, err := strconv.ParseFloat(loyaltyEarnEntry.Text, 64)
+		if err != nil || earnAmount < 0 {
+			dialog.ShowError(fmt.Errorf("invalid loyalty earn amount"), s.window)
+			return
+		}
+		pointValue, err := strconv.ParseFloat(loyaltyValueEntry.Text, 64)
+		if err != nil || pointValue < 0 {
+			dialog.ShowError(fmt.Errorf("invalid loyalty point value"), s.window)
+			return
+		}
+
+		if len(supervisorPINEntry.Text) < 4 {
+			dialog.ShowError(fmt.Errorf("supervisor PIN must be at least 4 characters"), s.window)
+			return
+		}
+
+		lookahead, err := strconv.Atoi(expiryLookaheadEntry.Text)
+		if err != nil || lookahead < 0 {
+			dialog.ShowError(fmt.Errorf("invalid expiry lookahead"), s.window)
+			return
+		}
+
+		if _, err := time.LoadLocation(timeZoneEntry.Text); err != nil || timeZoneEntry.Text == "" {
+			dialog.ShowError(fmt.Errorf("unknown time zone %q", timeZoneEntry.Text), s.window)
+			return
+		}
+
 		// Start transaction
 		tx, err := s.database.Begin()
 		if err != nil {
@@ -142,6 +201,17 @@ func (s *SettingsWindow) createSettingsContent() fyne.CanvasObject {
 			"store_phone":    storePhoneEntry.Text,
 			"tax_percentage": taxEntry.Text,
 			"invoice_prefix": invoicePrefixEntry.Text,
+
+			"loyalty_enabled":     strconv.FormatBool(loyaltyEnabledCheck.Checked),
+			"loyalty_earn_amount": fmt.Sprintf("%.2f", earnAmount),
+			"loyalty_point_value": fmt.Sprintf("%.2f", pointValue),
+			"loyalty_redeem_mode": loyaltyModeSelect.Selected,
+
+			"supervisor_pin": supervisorPINEntry.Text,
+
+			"expiry_lookahead_days": strconv.Itoa(lookahead),
+
+			"time_zone": timeZoneEntry.Text,
 		}
 
 		for key, value := range updates {
@@ -209,6 +279,35 @@ func (s *SettingsWindow) createSettingsContent() fyne.CanvasObject {
 				resetInvoiceButton,
 			),
 		),
+		widget.NewCard("Loyalty Program", "",
+			container.NewVBox(
+				loyaltyEnabledCheck,
+				widget.NewLabel("Spend per Point"),
+				loyaltyEarnEntry,
+				widget.NewLabel("Point Value"),
+				loyaltyValueEntry,
+				widget.NewLabel("Redeem Points As"),
+				loyaltyModeSelect,
+			),
+		),
+		widget.NewCard("Security", "",
+			container.NewVBox(
+				widget.NewLabel("Supervisor PIN"),
+				supervisorPINEntry,
+			),
+		),
+		widget.NewCard("Expiry Tracking", "",
+			container.NewVBox(
+				widget.NewLabel("Expiring Soon Lookahead (days)"),
+				expiryLookaheadEntry,
+			),
+		),
+		widget.NewCard("Reporting", "",
+			container.NewVBox(
+				widget.NewLabel("Store Time Zone"),
+				timeZoneEntry,
+			),
+		),
 		widget.NewCard("Printer Settings", "",
 			container.NewVBox(
 				widget.NewLabel("Printer Name"),
@@ -223,5 +322,5 @@ func (s *SettingsWindow) createSettingsContent() fyne.CanvasObject {
 		saveButton,
 	)
 
-	return container.NewBorder(header, nil, nil, nil, form)
+	return container.NewBorder(header, nil, nil, nil, container.NewVScroll(form))
 }
@@ -0,0 +1,383 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hendrisulistya/cashier-app/db"
+	"github.com/hendrisulistya/cashier-app/types"
+)
+
+type StocktakeWindow struct {
+	window     fyne.Window
+	database   *sql.DB
+	list       *widget.List
+	stocktakes []types.Stocktake
+}
+
+func NewStocktakeWindow(window fyne.Window, database *sql.DB) *StocktakeWindow {
+	return &StocktakeWindow{
+		window:   window,
+		database: database,
+	}
+}
+
+func (s *StocktakeWindow) Load() error {
+	var err error
+	s.stocktakes, err = db.GetStocktakes(s.database)
+	if err != nil {
+		return fmt.Errorf("could not fetch stocktakes: %v", err)
+	}
+
+	content := s.createStocktakeContent()
+	s.window.SetContent(content)
+	return nil
+}
+
+func (s *StocktakeWindow) createStocktakeContent() fyne.CanvasObject {
+	// Back button
+	backButton := widget.NewButton("Back to Inventory", func() {
+		inventoryWindow := NewInventoryWindow(s.window, s.database)
+		if err := inventoryWindow.Load(); err != nil {
+			log.Printf("Error returning to inventory: %v", err)
+		}
+	})
+
+	// Header
+	header := container.NewHBox(
+		backButton,
+		widget.NewLabel("Stocktakes"),
+	)
+
+	s.list = widget.NewList(
+		func() int { return len(s.stocktakes) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabel(""),                 // Name
+				widget.NewLabel(""),                 // Status
+				widget.NewLabel(""),                 // Scope
+				widget.NewLabel(""),                 // Created
+				widget.NewButton("Open", func() {}), // Open button placeholder
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			stocktake := s.stocktakes[id]
+			box := item.(*fyne.Container)
+
+			box.Objects[0].(*widget.Label).SetText(stocktake.Name)
+			box.Objects[1].(*widget.Label).SetText(stocktake.Status)
+			box.Objects[2].(*widget.Label).SetText(stocktakeScope(stocktake))
+			box.Objects[3].(*widget.Label).SetText(fmt.Sprintf("%s by %s",
+				stocktake.CreatedAt.Format("2006-01-02 15:04"), stocktake.CreatedBy))
+			box.Objects[4].(*widget.Button).OnTapped = func() {
+				s.openStocktake(stocktake.ID)
+			}
+		},
+	)
+
+	newButton := widget.NewButton("New Stocktake", func() {
+		s.showNewStocktakeDialog()
+	})
+	newButton.Importance = widget.HighImportance
+
+	return container.NewBorder(header, newButton, nil, nil, container.NewScroll(s.list))
+}
+
+func (s *StocktakeWindow) showNewStocktakeDialog() {
+	categories, err := db.GetCategories(s.database)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("could not fetch categories: %v", err), s.window)
+		return
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(fmt.Sprintf("Stocktake %s", time.Now().Format("2006-01-02")))
+
+	categorySelect := widget.NewSelect(append([]string{"All categories"}, categories...), nil)
+	categorySelect.SetSelectedIndex(0)
+
+	shelfEntry := widget.NewEntry()
+	shelfEntry.SetPlaceHolder("Leave empty for all shelves")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Category", categorySelect),
+		widget.NewFormItem("Shelf", shelfEntry),
+	}
+
+	dialog.ShowForm("New Stocktake", "Start", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			category := ""
+			if categorySelect.SelectedIndex() > 0 {
+				category = categorySelect.Selected
+			}
+
+			id, err := db.CreateStocktake(s.database, nameEntry.Text, category, shelfEntry.Text, currentUser)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to start stocktake: %v", err), s.window)
+				return
+			}
+
+			s.openStocktake(id)
+		}, s.window)
+}
+
+// openStocktake shows the count screen for a single session
+func (s *StocktakeWindow) openStocktake(id int) {
+	stocktake, err := db.GetStocktake(s.database, id)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("could not load stocktake: %v", err), s.window)
+		return
+	}
+
+	backButton := widget.NewButton("Back to Stocktakes", func() {
+		if err := s.Load(); err != nil {
+			log.Printf("Error returning to stocktakes: %v", err)
+		}
+	})
+
+	header := container.NewHBox(
+		backButton,
+		widget.NewLabel(fmt.Sprintf("%s (%s) - %s", stocktake.Name, stocktakeScope(stocktake), stocktake.Status)),
+	)
+
+	// One row per product with an entry for the counted quantity
+	editable := stocktake.Status == types.StocktakeCounting
+	countEntries := map[int]*widget.Entry{}
+	rows := container.NewVBox(
+		container.NewGridWithColumns(6,
+			widget.NewLabelWithStyle("Shelf", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Product", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Expected", fyne.TextAlignTrailing, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Counted", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Variance", fyne.TextAlignTrailing, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Value", fyne.TextAlignTrailing, fyne.TextStyle{Bold: true}),
+		),
+	)
+	for _, line := range stocktake.Lines {
+		countEntry := widget.NewEntry()
+		countEntry.SetPlaceHolder(line.Product.Unit)
+		if line.Counted != nil {
+			countEntry.SetText(types.FormatQuantity(*line.Counted, line.Product.Precision))
+		}
+		if !editable {
+			countEntry.Disable()
+		}
+		countEntries[line.ID] = countEntry
+
+		rows.Add(container.NewGridWithColumns(6,
+			widget.NewLabel(line.Product.Shelf),
+			widget.NewLabel(line.Product.Name),
+			widget.NewLabelWithStyle(line.Product.FormatQuantity(line.Expected), fyne.TextAlignTrailing, fyne.TextStyle{}),
+			countEntry,
+			widget.NewLabelWithStyle(types.FormatQuantity(line.Variance(), line.Product.Precision), fyne.TextAlignTrailing, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("Rp%.2f", line.VarianceValue()), fyne.TextAlignTrailing, fyne.TextStyle{}),
+		))
+	}
+
+	// saveCounts stores the entered counts and reloads the session
+	saveCounts := func(onSaved func()) {
+		counts := map[int]*float64{}
+		for _, line := range stocktake.Lines {
+			text := countEntries[line.ID].Text
+			if text == "" {
+				counts[line.ID] = nil
+				continue
+			}
+			counted, err := strconv.ParseFloat(text, 64)
+			if err != nil || counted < 0 {
+				dialog.ShowError(fmt.Errorf("invalid count for %s", line.Product.Name), s.window)
+				return
+			}
+			counted = line.Product.RoundQuantity(counted)
+			counts[line.ID] = &counted
+		}
+
+		if err := db.SaveStocktakeCounts(s.database, stocktake.ID, counts, currentUser); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save counts: %v", err), s.window)
+			return
+		}
+		onSaved()
+	}
+
+	saveButton := widget.NewButton("Save Counts", func() {
+		saveCounts(func() { s.openStocktake(stocktake.ID) })
+	})
+	saveButton.Importance = widget.HighImportance
+
+	countSheetButton := widget.NewButton("Print Count Sheet", func() {
+		printDocument(s.window, "count_sheet", formatCountSheet(stocktake))
+	})
+
+	varianceButton := widget.NewButton("Variance Report", func() {
+		report := formatVarianceReport(stocktake)
+		scroll := container.NewScroll(widget.NewTextGridFromString(report))
+		scroll.SetMinSize(fyne.NewSize(700, 400))
+		dialog.ShowCustomConfirm("Variance Report", "Print", "Close", scroll, func(print bool) {
+			if print {
+				printDocument(s.window, "variance_report", report)
+			}
+		}, s.window)
+	})
+
+	approveButton := widget.NewButton("Approve and Post", func() {
+		saveCounts(func() {
+			s.showApproveDialog(stocktake.ID)
+		})
+	})
+
+	cancelButton := widget.NewButton("Cancel Stocktake", func() {
+		dialog.ShowConfirm("Cancel Stocktake",
+			"Are you sure you want to cancel this stocktake? No stock will be changed.",
+			func(confirm bool) {
+				if !confirm {
+					return
+				}
+				if err := db.CancelStocktake(s.database, stocktake.ID); err != nil {
+					dialog.ShowError(fmt.Errorf("failed to cancel stocktake: %v", err), s.window)
+					return
+				}
+				s.openStocktake(stocktake.ID)
+			}, s.window)
+	})
+	cancelButton.Importance = widget.DangerImportance
+
+	if !editable {
+		saveButton.Disable()
+		approveButton.Disable()
+		cancelButton.Disable()
+	}
+
+	buttons := container.NewHBox(
+		saveButton,
+		countSheetButton,
+		varianceButton,
+		layout.NewSpacer(),
+		cancelButton,
+		approveButton,
+	)
+
+	s.window.SetContent(container.NewBorder(header, buttons, nil, nil, container.NewVScroll(rows)))
+}
+
+// showApproveDialog asks for the supervisor PIN before posting adjustments
+func (s *StocktakeWindow) showApproveDialog(stocktakeID int) {
+	pinEntry := widget.NewPasswordEntry()
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Supervisor PIN", pinEntry),
+	}
+
+	dialog.ShowForm("Approve Stocktake", "Approve", "Cancel", items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+
+			ok, err := db.VerifySupervisorPIN(s.database, pinEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to verify PIN: %v", err), s.window)
+				return
+			}
+			if !ok {
+				dialog.ShowError(fmt.Errorf("incorrect supervisor PIN"), s.window)
+				return
+			}
+
+			if err := db.PostStocktake(s.database, stocktakeID, currentUser); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to post stocktake: %v", err), s.window)
+				return
+			}
+
+			dialog.ShowInformation("Stocktake Posted", "Stock has been adjusted to the counted quantities", s.window)
+			s.openStocktake(stocktakeID)
+		}, s.window)
+}
+
+func stocktakeScope(stocktake types.Stocktake) string {
+	category := stocktake.Category
+	if category == "" {
+		category = "All categories"
+	}
+	if stocktake.Shelf == "" {
+		return category
+	}
+	return fmt.Sprintf("%s, shelf %s", category, stocktake.Shelf)
+}
+
+// formatCountSheet builds a blind count sheet; expected quantities are left
+// off so counters record what they see
+func formatCountSheet(stocktake types.Stocktake) string {
+	sheet := "=================================================\n"
+	sheet += fmt.Sprintf("COUNT SHEET - %s\n", stocktake.Name)
+	sheet += fmt.Sprintf("Scope: %s\n", stocktakeScope(stocktake))
+	sheet += fmt.Sprintf("Printed: %s\n", time.Now().Format("2006-01-02 15:04"))
+	sheet += "=================================================\n"
+	sheet += fmt.Sprintf("%-8s %-22s %-6s %s\n", "Shelf", "Product", "Unit", "Counted")
+	sheet += "-------------------------------------------------\n"
+	for _, line := range stocktake.Lines {
+		sheet += fmt.Sprintf("%-8s %-22s %-6s __________\n", line.Product.Shelf, line.Product.Name, line.Product.Unit)
+	}
+	sheet += "-------------------------------------------------\n"
+	sheet += "Counted by: ______________  Date: ____________\n"
+	return sheet
+}
+
+func formatVarianceReport(stocktake types.Stocktake) string {
+	report := fmt.Sprintf("Variance Report - %s\n", stocktake.Name)
+	report += fmt.Sprintf("Scope: %s\n", stocktakeScope(stocktake))
+	report += fmt.Sprintf("Status: %s\n", stocktake.Status)
+	if stocktake.PostedAt != nil {
+		report += fmt.Sprintf("Approved by %s on %s\n", stocktake.ApprovedBy, stocktake.PostedAt.Format("2006-01-02 15:04"))
+	}
+	report += "\n"
+	report += fmt.Sprintf("%-8s | %-20s | %10s | %10s | %10s | %s\n",
+		"Shelf", "Product", "Expected", "Counted", "Variance", "Value")
+	report += "--------------------------------------------------------------------------------\n"
+
+	var counted, uncounted int
+	var gain, loss float64
+	for _, line := range stocktake.Lines {
+		countedText := "-"
+		if line.Counted != nil {
+			countedText = types.FormatQuantity(*line.Counted, line.Product.Precision)
+			counted++
+		} else {
+			uncounted++
+		}
+
+		value := line.VarianceValue()
+		if value > 0 {
+			gain += value
+		} else {
+			loss += value
+		}
+
+		report += fmt.Sprintf("%-8s | %-20s | %10s | %10s | %10s | Rp%.2f\n",
+			line.Product.Shelf,
+			line.Product.Name,
+			types.FormatQuantity(line.Expected, line.Product.Precision),
+			countedText,
+			types.FormatQuantity(line.Variance(), line.Product.Precision),
+			value)
+	}
+
+	report += "--------------------------------------------------------------------------------\n"
+	report += fmt.Sprintf("Products counted:   %d of %d\n", counted, counted+uncounted)
+	report += fmt.Sprintf("Variance gain:      Rp%.2f\n", gain)
+	report += fmt.Sprintf("Variance loss:      Rp%.2f\n", loss)
+	report += fmt.Sprintf("Net variance value: Rp%.2f\n", gain+loss)
+	return report
+}